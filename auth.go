@@ -0,0 +1,81 @@
+package pokebot
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"google.golang.org/api/idtoken"
+)
+
+// authConfig protects manual publishes. A request is authorized when its
+// bearer token is either the shared secret or a Google-signed OIDC ID token
+// for the audience, e.g. one sent by Cloud Scheduler or
+// `gcloud auth print-identity-token`.
+type authConfig struct {
+	Secret string
+
+	Audience string
+	// Emails restricts OIDC tokens to these identities. Any identity is
+	// accepted when it is empty.
+	Emails []string
+}
+
+// authConfigFromEnv reads PUBLISH_SECRET, PUBLISH_OIDC_AUDIENCE and
+// PUBLISH_OIDC_EMAILS (comma-separated).
+func authConfigFromEnv() authConfig {
+	cfg := authConfig{
+		Secret:   os.Getenv("PUBLISH_SECRET"),
+		Audience: os.Getenv("PUBLISH_OIDC_AUDIENCE"),
+	}
+
+	if emails := os.Getenv("PUBLISH_OIDC_EMAILS"); emails != "" {
+		for _, email := range strings.Split(emails, ",") {
+			cfg.Emails = append(cfg.Emails, strings.TrimSpace(email))
+		}
+	}
+
+	return cfg
+}
+
+// authorize returns an error if r is not allowed to publish on demand.
+// Manual publishes are disabled until a secret or an audience is configured.
+func (cfg authConfig) authorize(r *http.Request) error {
+	if cfg.Secret == "" && cfg.Audience == "" {
+		return fmt.Errorf("manual publishing is disabled; set PUBLISH_SECRET or PUBLISH_OIDC_AUDIENCE to enable it")
+	}
+
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		return fmt.Errorf("missing bearer token")
+	}
+
+	if cfg.Secret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(cfg.Secret)) == 1 {
+		return nil
+	}
+
+	if cfg.Audience == "" {
+		return fmt.Errorf("invalid bearer token")
+	}
+
+	payload, err := idtoken.Validate(r.Context(), token, cfg.Audience)
+	if err != nil {
+		return fmt.Errorf("invalid bearer token: %w", err)
+	}
+
+	if len(cfg.Emails) == 0 {
+		return nil
+	}
+
+	email, _ := payload.Claims["email"].(string)
+	verified, _ := payload.Claims["email_verified"].(bool)
+	for _, allowed := range cfg.Emails {
+		if verified && email == allowed {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s is not allowed to publish", email)
+}
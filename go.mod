@@ -6,6 +6,9 @@ require (
 	github.com/go-resty/resty/v2 v2.15.3
 	github.com/rs/zerolog v1.33.0
 	github.com/vicanso/go-charts/v2 v2.6.10
+	golang.org/x/oauth2 v0.23.0
+	google.golang.org/api v0.203.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -56,10 +59,8 @@ require (
 	go.uber.org/zap v1.10.0 // indirect
 	golang.org/x/crypto v0.28.0 // indirect
 	golang.org/x/image v0.0.0-20200927104501-e162460cd6b5 // indirect
-	golang.org/x/oauth2 v0.23.0 // indirect
 	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/time v0.7.0 // indirect
-	google.golang.org/api v0.203.0 // indirect
 	google.golang.org/genproto v0.0.0-20241015192408-796eee8c2d53 // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20241007155032-5fefd90f89a9 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20241015192408-796eee8c2d53 // indirect
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/pokemon"
+	"github.com/rs/zerolog/log"
+)
+
+const historyUsage = `usage:
+  history export [-backend B] [-dir D] [-out FILE]
+  history import -in FILE [-backend B] [-dir D] [-dry-run]
+  history migrate -from B -to B [-from-dir D] [-to-dir D] [-dry-run]`
+
+// historyCommand runs the history subcommand with args, the arguments after
+// "history".
+func historyCommand(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, historyUsage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch args[0] {
+	case "export":
+		err = exportHistory(args[1:])
+	case "import":
+		err = importHistory(args[1:])
+	case "migrate":
+		err = migrateHistory(args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, historyUsage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Err(err).Msg(err.Error())
+		os.Exit(1)
+	}
+}
+
+// storageFlags adds the flags name and dirName choosing a backend to flags,
+// defaulting to STORAGE_BACKEND and STORAGE_DIR.
+func storageFlags(flags *flag.FlagSet, name, dirName, usage string) (*string, *string) {
+	cfg, _ := pokemon.StorageConfigFromEnv()
+
+	backend := flags.String(name, cfg.Backend, usage+": gcs, s3, local or memory")
+	dir := flags.String(dirName, cfg.Dir, "directory of the local backend for -"+name)
+
+	return backend, dir
+}
+
+// openBucket opens backend with the rest of its settings read from the
+// environment.
+func openBucket(ctx context.Context, backend, dir string) (cloud.FileBucket, error) {
+	cfg, err := pokemon.StorageConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Backend = backend
+	cfg.Dir = dir
+
+	return pokemon.NewBucket(ctx, cfg)
+}
+
+func exportHistory(args []string) error {
+	flags := flag.NewFlagSet("history export", flag.ExitOnError)
+	backend, dir := storageFlags(flags, "backend", "dir", "backend to export from")
+	out := flags.String("out", "", "file to write the JSONL records to (default stdout)")
+	flags.Parse(args)
+
+	ctx := context.Background()
+	bucket, err := openBucket(ctx, *backend, *dir)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", *out, err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	count, err := pokemon.ExportHistory(ctx, bucket, w)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "exported %d history records\n", count)
+	return nil
+}
+
+func importHistory(args []string) error {
+	flags := flag.NewFlagSet("history import", flag.ExitOnError)
+	backend, dir := storageFlags(flags, "backend", "dir", "backend to import into")
+	in := flags.String("in", "", "JSONL file to read the records from, or - for stdin")
+	dryRun := flags.Bool("dry-run", false, "only list the records that would be written")
+	flags.Parse(args)
+
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	ctx := context.Background()
+	bucket, err := openBucket(ctx, *backend, *dir)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		file, err := os.Open(*in)
+		if err != nil {
+			return fmt.Errorf("failed to open %s: %w", *in, err)
+		}
+		defer file.Close()
+		r = file
+	}
+
+	diff, err := pokemon.ImportHistory(ctx, bucket, r, *dryRun)
+	printDiff(diff, *dryRun)
+	return err
+}
+
+func migrateHistory(args []string) error {
+	flags := flag.NewFlagSet("history migrate", flag.ExitOnError)
+	from, fromDir := storageFlags(flags, "from", "from-dir", "backend to migrate from")
+	to, toDir := storageFlags(flags, "to", "to-dir", "backend to migrate to")
+	dryRun := flags.Bool("dry-run", false, "only list the objects that would be written")
+	flags.Parse(args)
+
+	if *from == *to && (*from != pokemon.StorageLocal || *fromDir == *toDir) {
+		return fmt.Errorf("-from and -to are the same backend")
+	}
+
+	ctx := context.Background()
+	source, err := openBucket(ctx, *from, *fromDir)
+	if err != nil {
+		return err
+	}
+	target, err := openBucket(ctx, *to, *toDir)
+	if err != nil {
+		return err
+	}
+
+	diff, err := pokemon.MigrateHistory(ctx, source, target, *dryRun)
+	printDiff(diff, *dryRun)
+	return err
+}
+
+func printDiff(diff pokemon.HistoryDiff, dryRun bool) {
+	for _, object := range diff.Added {
+		fmt.Printf("+ %s\n", object)
+	}
+	for _, object := range diff.Updated {
+		fmt.Printf("~ %s\n", object)
+	}
+
+	verb := "wrote"
+	if dryRun {
+		verb = "would write"
+	}
+	fmt.Printf("%s %d new and %d updated objects, %d unchanged\n", verb, len(diff.Added), len(diff.Updated), diff.Unchanged)
+}
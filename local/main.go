@@ -1,12 +1,54 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"os"
+
 	"github.com/rickrollrumble/random-pokemon-publisher/services/pokemon"
 	"github.com/rs/zerolog/log"
 )
 
 func main() {
-	res, err := pokemon.Publish()
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		historyCommand(os.Args[2:])
+		return
+	}
+
+	previewDays := flag.Int("preview", 0, "list the pokemon that would be published over the next N days instead of publishing")
+	id := flag.Int("id", 0, "publish the pokemon with this PokeAPI ID instead of the day's pick")
+	name := flag.String("name", "", "publish the pokemon with this name, e.g. pikachu, instead of the day's pick")
+	form := flag.String("form", "", "with -id or -name, publish this form of the species, e.g. alola")
+	shiny := flag.Bool("shiny", false, "with -id or -name, publish the shiny artwork")
+	force := flag.Bool("force", false, "with -id or -name, publish even if the pokemon has been published before")
+	dryRun := flag.Bool("dry-run", false, "render the post into -dry-run-dir instead of publishing it")
+	dryRunDir := flag.String("dry-run-dir", "dry-run", "directory the post records and images of a dry run are written to")
+	flag.Parse()
+
+	if *previewDays > 0 {
+		preview(*previewDays)
+		return
+	}
+
+	opts := pokemon.PublishOptions{DryRun: *dryRun, DryRunDir: *dryRunDir}
+	if *id != 0 || *name != "" {
+		opts.Override = &pokemon.Override{
+			ID:    *id,
+			Name:  *name,
+			Form:  *form,
+			Shiny: *shiny,
+			Force: *force,
+		}
+		if err := opts.Override.Validate(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	} else if *form != "" || *shiny || *force {
+		fmt.Fprintln(os.Stderr, "-form, -shiny and -force need -id or -name")
+		os.Exit(2)
+	}
+
+	res, err := pokemon.Publish(opts)
 
 	if err != nil {
 		log.Err(err).Msg(err.Error())
@@ -14,3 +56,24 @@ func main() {
 		log.Info().Msg(res)
 	}
 }
+
+func preview(days int) {
+	entries, err := pokemon.Preview(days)
+	for _, entry := range entries {
+		line := fmt.Sprintf("%s  #%-5d %s", entry.Date, entry.PokemonID, entry.Name)
+		if entry.Shiny {
+			line += " (shiny)"
+		}
+		if entry.Theme != "" {
+			line += fmt.Sprintf("  {%s}", entry.Theme)
+		}
+		if entry.Progress != "" {
+			line += fmt.Sprintf("  [%s]", entry.Progress)
+		}
+		fmt.Println(line)
+	}
+
+	if err != nil {
+		log.Err(err).Msg(err.Error())
+	}
+}
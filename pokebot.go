@@ -1,21 +1,135 @@
 package pokebot
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"mime"
 	"net/http"
+	"strconv"
 
 	"github.com/GoogleCloudPlatform/functions-framework-go/functions"
 	"github.com/rickrollrumble/random-pokemon-publisher/services/pokemon"
+	"github.com/rs/zerolog/log"
 )
 
 func init() {
+	// a misconfigured storage backend keeps the function from starting, so
+	// it fails the deploy instead of the first scheduled run.
+	if err := pokemon.SetUpStorage(context.Background()); err != nil {
+		log.Fatal().Err(err).Msg("failed to set up storage")
+	}
+
 	functions.HTTP("Publish", publish)
 }
 
+// publishParams are the optional parameters of the Publish handler, given as
+// query parameters or as a JSON body.
+type publishParams struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Form  string `json:"form"`
+	Shiny bool   `json:"shiny"`
+	Force bool   `json:"force"`
+
+	// DryRun returns the rendered post as JSON instead of publishing it.
+	DryRun bool `json:"dry_run"`
+}
+
 func publish(w http.ResponseWriter, r *http.Request) {
-	resp, err := pokemon.Publish()
+	params, err := parsePublishParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// a dry run reveals the day's pick ahead of time, so it needs the same
+	// authorization as a manual publish.
+	if params.ID != 0 || params.Name != "" || params.DryRun {
+		if err := authConfigFromEnv().authorize(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	opts := pokemon.PublishOptions{}
+	if params.ID != 0 || params.Name != "" {
+		opts.Override = &pokemon.Override{
+			ID:    params.ID,
+			Name:  params.Name,
+			Form:  params.Form,
+			Shiny: params.Shiny,
+			Force: params.Force,
+		}
+		if err := opts.Override.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else if params.Form != "" || params.Shiny || params.Force {
+		http.Error(w, "form, shiny and force need an id or a name", http.StatusBadRequest)
+		return
+	}
+
+	if params.DryRun {
+		dryRun(w, opts)
+		return
+	}
+
+	resp, err := pokemon.Publish(opts)
 	if err != nil {
 		fmt.Fprintln(w, err.Error())
 	}
 	fmt.Fprintln(w, resp)
 }
+
+func dryRun(w http.ResponseWriter, opts pokemon.PublishOptions) {
+	draft, err := pokemon.DryRun(opts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(draft); err != nil {
+		fmt.Fprintln(w, err.Error())
+	}
+}
+
+func parsePublishParams(r *http.Request) (publishParams, error) {
+	var params publishParams
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if r.Body != nil && r.ContentLength != 0 && mediaType == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			return params, fmt.Errorf("request body is not a valid JSON: %w", err)
+		}
+	}
+
+	query := r.URL.Query()
+
+	if id := query.Get("id"); id != "" {
+		parsed, err := strconv.Atoi(id)
+		if err != nil {
+			return params, fmt.Errorf("id must be a number, got %q", id)
+		}
+		params.ID = parsed
+	}
+	if name := query.Get("name"); name != "" {
+		params.Name = name
+	}
+	if form := query.Get("form"); form != "" {
+		params.Form = form
+	}
+
+	for key, value := range map[string]*bool{"shiny": &params.Shiny, "force": &params.Force, "dry_run": &params.DryRun} {
+		if raw := query.Get(key); raw != "" {
+			parsed, err := strconv.ParseBool(raw)
+			if err != nil {
+				return params, fmt.Errorf("%s must be a boolean, got %q", key, raw)
+			}
+			*value = parsed
+		}
+	}
+
+	return params, nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"regexp"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -14,6 +15,9 @@ import (
 
 var repo = os.Getenv("BSKY_HANDLE")
 
+// hashtagPattern matches the hashtags that are turned into tag facets.
+var hashtagPattern = regexp.MustCompile(`#[\p{L}][\p{L}\p{N}_]*`)
+
 // Import resty into your code and refer it as `resty`.
 func CreateNewSession() (NewSession, error) {
 	client := resty.New().SetBaseURL("https://bsky.social").SetRetryCount(5)
@@ -38,54 +42,93 @@ func CreateNewSession() (NewSession, error) {
 	return bskyResp, nil
 }
 
-func SendPost(ctx context.Context, params PostParams) error {
+func SendPost(ctx context.Context, params PostParams) (RespCreatePost, error) {
+	var createPostResp RespCreatePost
+
 	session, ok := ctx.Value("session").(NewSession)
 	if !ok {
 		var sessionCreateErr error
 		session, sessionCreateErr = CreateNewSession()
 		if sessionCreateErr != nil {
-			return fmt.Errorf("failed to create new post: %w", sessionCreateErr)
+			return createPostResp, fmt.Errorf("failed to create new post: %w", sessionCreateErr)
 		}
 	}
 
 	client := resty.New().SetAuthScheme("Bearer").SetBaseURL("https://bsky.social")
 	client.SetAuthToken(session.AccessJwt)
 
-	req := client.R().SetBody(createPostBody(params))
+	req := client.R().SetBody(CreatePostBody(params))
 
 	resp, respErr := req.Post("xrpc/com.atproto.repo.createRecord")
 	if respErr != nil {
-		return fmt.Errorf("failed to make request to create new post: %w", respErr)
+		return createPostResp, fmt.Errorf("failed to make request to create new post: %w", respErr)
 	}
 
 	if resp.IsError() {
-		return fmt.Errorf("received an error response while trying to create a new post: %v", string(resp.Body()))
+		return createPostResp, fmt.Errorf("received an error response while trying to create a new post: %v", string(resp.Body()))
 	}
 
-	var createPostResp RespCreatePost
 	unmarshalErr := json.Unmarshal(resp.Body(), &createPostResp)
 	if unmarshalErr != nil {
-		return fmt.Errorf("received an invalid response while trying to create post: %w", unmarshalErr)
+		return createPostResp, fmt.Errorf("received an invalid response while trying to create post: %w", unmarshalErr)
 	}
 
 	if reflect.ValueOf(createPostResp).IsZero() {
-		return fmt.Errorf("received a json response in invalid format while creating post")
+		return createPostResp, fmt.Errorf("received a json response in invalid format while creating post")
+	}
+
+	return createPostResp, nil
+}
+
+// SendThread publishes first and then each of replies as a reply to the
+// previous post, all under the same thread root.
+func SendThread(ctx context.Context, first PostParams, replies []string) (RespCreatePost, error) {
+	// share one session across the whole thread instead of logging in for
+	// every post.
+	if _, ok := ctx.Value("session").(NewSession); !ok {
+		session, sessionCreateErr := CreateNewSession()
+		if sessionCreateErr != nil {
+			return RespCreatePost{}, fmt.Errorf("failed to create new thread: %w", sessionCreateErr)
+		}
+		ctx = context.WithValue(ctx, "session", session)
+	}
+
+	root, err := SendPost(ctx, first)
+	if err != nil {
+		return root, err
+	}
+
+	rootRef := StrongRef{URI: root.URI, Cid: root.Cid}
+	parentRef := rootRef
+
+	for i, reply := range replies {
+		resp, err := SendPost(ctx, PostParams{
+			Text:  reply,
+			Reply: &ReplyRef{Root: rootRef, Parent: parentRef},
+		})
+		if err != nil {
+			return root, fmt.Errorf("failed to send reply %d of thread: %w", i+1, err)
+		}
+
+		parentRef = StrongRef{URI: resp.URI, Cid: resp.Cid}
 	}
 
-	return nil
+	return root, nil
 }
 
-func createPostBody(params PostParams) ReqCreatePost {
+// CreatePostBody builds the createRecord request that publishes params.
+func CreatePostBody(params PostParams) ReqCreatePost {
 	post := ReqCreatePost{
 		Repo:       repo,
 		Collection: "app.bsky.feed.post",
 		Record: Record{
-			Text:      fmt.Sprintf("%s %s", params.Text, params.Link),
+			Text:      params.Text,
 			CreatedAt: time.Now().Format(time.RFC3339),
 		},
 	}
 
 	if params.Link != "" {
+		post.Record.Text = fmt.Sprintf("%s %s", params.Text, params.Link)
 		post.Record.Facets = append(post.Record.Facets, Facet{
 			Index: Index{
 				ByteStart: len(params.Text),
@@ -100,24 +143,30 @@ func createPostBody(params PostParams) ReqCreatePost {
 		})
 	}
 
-	post.Record.Facets = append(post.Record.Facets, Facet{
-		Index: Index{
-			ByteStart: 8,
-			ByteEnd:   16,
-		},
-		Features: []Features{
-			{
-				Type: "app.bsky.richtext.facet#tag",
-				Tag:  "Pokemon",
+	for _, index := range hashtagPattern.FindAllStringIndex(params.Text, -1) {
+		post.Record.Facets = append(post.Record.Facets, Facet{
+			Index: Index{
+				ByteStart: index[0],
+				ByteEnd:   index[1],
 			},
-		},
-	})
+			Features: []Features{
+				{
+					Type: "app.bsky.richtext.facet#tag",
+					Tag:  params.Text[index[0]+1 : index[1]],
+				},
+			},
+		})
+	}
 
 	if len(params.Images) > 0 {
-		post.Record.Embed.Type = "app.bsky.embed.images"
-		post.Record.Embed.Images = params.Images
+		post.Record.Embed = &Embed{
+			Type:   "app.bsky.embed.images",
+			Images: params.Images,
+		}
 	}
 
+	post.Record.Reply = params.Reply
+
 	return post
 }
 
@@ -0,0 +1,91 @@
+package bluesky
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxPostLength is the maximum length of a post's text in graphemes.
+const MaxPostLength = 300
+
+// textLength approximates the grapheme count Bluesky enforces by counting
+// runes, which is exact for the text the bot generates.
+func textLength(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// FitsInPost reports whether text is short enough to be published as a
+// single post.
+func FitsInPost(text string) bool {
+	return textLength(text) <= MaxPostLength
+}
+
+// SplitIntoPosts packs sections, in order, into as few posts as possible
+// without splitting a section. Sections are separated by a blank line. A
+// section too long for a post of its own is split on line boundaries, and
+// a single line too long for a post is truncated.
+func SplitIntoPosts(sections []string) []string {
+	posts := []string{}
+	current := ""
+
+	for _, section := range sections {
+		for _, part := range splitSection(section) {
+			candidate := part
+			if current != "" {
+				candidate = current + "\n\n" + part
+			}
+
+			if FitsInPost(candidate) {
+				current = candidate
+				continue
+			}
+
+			if current != "" {
+				posts = append(posts, current)
+			}
+			current = part
+		}
+	}
+
+	if current != "" {
+		posts = append(posts, current)
+	}
+
+	return posts
+}
+
+// splitSection breaks a section that does not fit in a post into parts that
+// do.
+func splitSection(section string) []string {
+	if FitsInPost(section) {
+		return []string{section}
+	}
+
+	parts := []string{}
+	current := ""
+
+	for _, line := range strings.Split(section, "\n") {
+		if !FitsInPost(line) {
+			line = string([]rune(line)[:MaxPostLength-1]) + "…"
+		}
+
+		candidate := line
+		if current != "" {
+			candidate = current + "\n" + line
+		}
+
+		if FitsInPost(candidate) {
+			current = candidate
+			continue
+		}
+
+		parts = append(parts, current)
+		current = line
+	}
+
+	if current != "" {
+		parts = append(parts, current)
+	}
+
+	return parts
+}
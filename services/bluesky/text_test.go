@@ -0,0 +1,70 @@
+package bluesky
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitIntoPosts(t *testing.T) {
+	// three of these lines fit in a post on consecutive lines, but not with
+	// blank lines between them
+	line := strings.Repeat("a", 99)
+	full := strings.Repeat("b", MaxPostLength)
+
+	tests := []struct {
+		name     string
+		sections []string
+		want     []string
+	}{
+		{
+			name:     "everything fits",
+			sections: []string{"intro", "flavor"},
+			want:     []string{"intro\n\nflavor"},
+		},
+		{
+			name:     "a section that fits exactly",
+			sections: []string{full},
+			want:     []string{full},
+		},
+		{
+			name:     "sections moved to a reply",
+			sections: []string{full, "evolution"},
+			want:     []string{full, "evolution"},
+		},
+		{
+			name:     "sections are not split",
+			sections: []string{line, line, line},
+			want:     []string{line + "\n\n" + line, line},
+		},
+		{
+			name:     "long section split on lines",
+			sections: []string{"intro", strings.Join([]string{line, line, line, line}, "\n")},
+			want:     []string{"intro", line + "\n" + line + "\n" + line, line},
+		},
+		{
+			name:     "long line truncated",
+			sections: []string{full + "c"},
+			want:     []string{strings.Repeat("b", MaxPostLength-1) + "…"},
+		},
+		{
+			name:     "graphemes rather than bytes",
+			sections: []string{strings.Repeat("é", MaxPostLength)},
+			want:     []string{strings.Repeat("é", MaxPostLength)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitIntoPosts(tt.sections)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitIntoPosts = %q, want %q", got, tt.want)
+			}
+			for i, post := range got {
+				if !FitsInPost(post) {
+					t.Errorf("post %d is %d long, want at most %d", i, textLength(post), MaxPostLength)
+				}
+			}
+		})
+	}
+}
@@ -74,17 +74,29 @@ type Embed struct {
 	Images []ImageDetails `json:"images"`
 }
 
+type StrongRef struct {
+	URI string `json:"uri"`
+	Cid string `json:"cid"`
+}
+
+type ReplyRef struct {
+	Root   StrongRef `json:"root"`
+	Parent StrongRef `json:"parent"`
+}
+
 type Record struct {
-	Text      string  `json:"text"`
-	CreatedAt string  `json:"createdAt"`
-	Facets    []Facet `json:"facets,omitempty"`
-	Embed     Embed   `json:"embed,omitempty"`
+	Text      string    `json:"text"`
+	CreatedAt string    `json:"createdAt"`
+	Facets    []Facet   `json:"facets,omitempty"`
+	Embed     *Embed    `json:"embed,omitempty"`
+	Reply     *ReplyRef `json:"reply,omitempty"`
 }
 
 type PostParams struct {
 	Text   string
 	Link   string
 	Images []ImageDetails
+	Reply  *ReplyRef
 }
 
 type RespImageUpload struct {
@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+)
+
+// ErrMiss is returned by a Store when no entry exists for a key.
+var ErrMiss = errors.New("cache miss")
+
+// Entry is a cached HTTP response along with the validators needed to
+// revalidate it against the origin.
+type Entry struct {
+	URL          string    `json:"url"`
+	Body         []byte    `json:"body"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+}
+
+// Fresh reports whether the entry can be served without revalidation.
+func (e Entry) Fresh(ttl time.Duration, now time.Time) bool {
+	return now.Sub(e.FetchedAt) < ttl
+}
+
+// Store persists cache entries keyed by URL.
+type Store interface {
+	Get(ctx context.Context, url string) (Entry, error)
+	Put(ctx context.Context, entry Entry) error
+}
+
+// key maps a URL to a name that is safe to use as a file or object name.
+func key(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+// DirStore keeps entries as JSON files in a local directory.
+type DirStore struct {
+	dir string
+}
+
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %s: %w", dir, err)
+	}
+
+	return &DirStore{dir: dir}, nil
+}
+
+func (s *DirStore) Get(ctx context.Context, url string) (Entry, error) {
+	var entry Entry
+
+	content, err := os.ReadFile(filepath.Join(s.dir, key(url)+".json"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return entry, ErrMiss
+		}
+		return entry, fmt.Errorf("failed to read cache entry for %s: %w", url, err)
+	}
+
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return entry, fmt.Errorf("cache entry for %s is not a valid JSON: %w", url, err)
+	}
+
+	return entry, nil
+}
+
+func (s *DirStore) Put(ctx context.Context, entry Entry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry for %s: %w", entry.URL, err)
+	}
+
+	// write to a temporary file first so a concurrent reader never sees a
+	// partially written entry.
+	tmp, err := os.CreateTemp(s.dir, "entry-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache entry for %s: %w", entry.URL, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write cache entry for %s: %w", entry.URL, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache entry for %s: %w", entry.URL, err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, key(entry.URL)+".json")); err != nil {
+		return fmt.Errorf("failed to save cache entry for %s: %w", entry.URL, err)
+	}
+
+	return nil
+}
+
+// BucketStore keeps entries as objects under a prefix of a cloud bucket.
+type BucketStore struct {
+	bucket cloud.FileBucket
+	prefix string
+}
+
+func NewBucketStore(bucket cloud.FileBucket, prefix string) *BucketStore {
+	return &BucketStore{bucket: bucket, prefix: prefix}
+}
+
+func (s *BucketStore) Get(ctx context.Context, url string) (Entry, error) {
+	var entry Entry
+
+	content, err := s.bucket.ReadFile(ctx, s.prefix+key(url))
+	if err != nil {
+		if errors.Is(err, cloud.ErrNotFound) {
+			return entry, ErrMiss
+		}
+		return entry, fmt.Errorf("failed to read cache entry for %s: %w", url, err)
+	}
+
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return entry, fmt.Errorf("cache entry for %s is not a valid JSON: %w", url, err)
+	}
+
+	return entry, nil
+}
+
+func (s *BucketStore) Put(ctx context.Context, entry Entry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache entry for %s: %w", entry.URL, err)
+	}
+
+	if err := s.bucket.CreateFile(ctx, s.prefix+key(entry.URL), content); err != nil {
+		return fmt.Errorf("failed to save cache entry for %s: %w", entry.URL, err)
+	}
+
+	return nil
+}
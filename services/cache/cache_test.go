@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud/local"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud/memory"
+)
+
+// TestBucketStore writes entries under a prefix the bucket has never seen,
+// as the PokeAPI cache does on its first run.
+func TestBucketStore(t *testing.T) {
+	tests := []struct {
+		name      string
+		newBucket func(t *testing.T) cloud.FileBucket
+	}{
+		{
+			name:      "memory",
+			newBucket: func(t *testing.T) cloud.FileBucket { return memory.NewBucket() },
+		},
+		{
+			name: "local",
+			newBucket: func(t *testing.T) cloud.FileBucket {
+				bucket, err := local.NewBucket(t.TempDir())
+				if err != nil {
+					t.Fatalf("NewBucket failed: %v", err)
+				}
+				return bucket
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewBucketStore(tt.newBucket(t), "pokeapi-cache/")
+			url := "https://pokeapi.co/api/v2/pokemon/25"
+
+			if _, err := store.Get(ctx, url); !errors.Is(err, ErrMiss) {
+				t.Fatalf("Get before Put returned %v, want ErrMiss", err)
+			}
+
+			entry := Entry{URL: url, Body: []byte(`{"id":25}`), ETag: `"abc"`, FetchedAt: time.Now().UTC().Truncate(time.Second)}
+			if err := store.Put(ctx, entry); err != nil {
+				t.Fatalf("Put failed: %v", err)
+			}
+
+			got, err := store.Get(ctx, url)
+			if err != nil {
+				t.Fatalf("Get failed: %v", err)
+			}
+			if string(got.Body) != string(entry.Body) || got.ETag != entry.ETag || !got.FetchedAt.Equal(entry.FetchedAt) {
+				t.Errorf("Get = %+v, want %+v", got, entry)
+			}
+		})
+	}
+}
@@ -1,61 +1,321 @@
 package aws
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"regexp"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
 )
 
-var (
-	bucketName         = os.Getenv("BUCKET_NAME")
-	awsRegion          = os.Getenv("AWS_DEFAULT_REGION")
-	awsAccessKey       = os.Getenv("AWS_ACCESS_KEY")
-	awsSecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
-)
+// DefaultTimeout bounds each call to S3 when Config.Timeout is not set.
+const DefaultTimeout = 30 * time.Second
+
+// Config configures a Bucket.
+type Config struct {
+	// Name is the name of the bucket.
+	Name string
+
+	Region          string
+	AccessKey       string
+	SecretAccessKey string
+
+	// Timeout bounds each call to S3, on top of the deadline of its context.
+	// It defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// Client, if set, is used instead of a client built from the region and
+	// the static credentials, e.g. for an S3-compatible endpoint.
+	Client s3iface.S3API
+}
+
+// ConfigFromEnv reads the bucket name from BUCKET_NAME, the region from
+// AWS_DEFAULT_REGION and the static credentials from AWS_ACCESS_KEY and
+// AWS_SECRET_ACCESS_KEY.
+func ConfigFromEnv() Config {
+	return Config{
+		Name:            os.Getenv("BUCKET_NAME"),
+		Region:          os.Getenv("AWS_DEFAULT_REGION"),
+		AccessKey:       os.Getenv("AWS_ACCESS_KEY"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+	}
+}
+
+// bucketNamePattern follows the S3 bucket naming rules.
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// validate checks that the bucket name is valid and, unless a client is
+// given, that the region and static credentials are set.
+func (c Config) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("BUCKET_NAME is not set")
+	}
+	if !bucketNamePattern.MatchString(c.Name) {
+		return fmt.Errorf("BUCKET_NAME %q is not a valid bucket name", c.Name)
+	}
 
-type Bucket struct{}
+	if c.Client != nil {
+		return nil
+	}
+
+	if c.Region == "" {
+		return fmt.Errorf("AWS_DEFAULT_REGION is not set")
+	}
 
-func createSession() *session.Session {
-	return session.Must(session.NewSession(&aws.Config{
-		Region:      aws.String(awsRegion),
-		Credentials: credentials.NewStaticCredentials(awsAccessKey, awsSecretAccessKey, ""),
-	}))
+	if c.AccessKey == "" || c.SecretAccessKey == "" {
+		return fmt.Errorf("AWS_ACCESS_KEY and AWS_SECRET_ACCESS_KEY must both be set")
+	}
+
+	return nil
+}
+
+// Bucket is an S3 bucket. It holds one client for its lifetime, so it is
+// meant to be created once and shared.
+type Bucket struct {
+	svc     s3iface.S3API
+	name    *string
+	timeout time.Duration
+}
+
+// NewBucket validates cfg and creates the S3 client of the bucket.
+func NewBucket(cfg Config) (*Bucket, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	svc := cfg.Client
+	if svc == nil {
+		sess, err := session.NewSession(&aws.Config{
+			Region:      aws.String(cfg.Region),
+			Credentials: credentials.NewStaticCredentials(cfg.AccessKey, cfg.SecretAccessKey, ""),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create aws session: %w", err)
+		}
+		svc = s3.New(sess)
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Bucket{svc: svc, name: aws.String(cfg.Name), timeout: timeout}, nil
+}
+
+func (b *Bucket) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, b.timeout)
 }
 
 func (b *Bucket) FileExists(ctx context.Context, fileName string) (bool, error) {
-	svc := s3.New(createSession())
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	_, err := b.svc.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: b.name,
+		Key:    aws.String(fileName),
+	})
+	if err != nil {
+		if s3Err, ok := err.(awserr.Error); ok && s3Err.Code() == "NotFound" {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check if %s exists in bucket: %w", fileName, classify(err))
+	}
+
+	return true, nil
+}
+
+func (b *Bucket) CreateFile(ctx context.Context, fileName string, content []byte) error {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	_, err := b.svc.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: b.name,
+		Key:    aws.String(fileName),
+		Body:   bytes.NewReader(content),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write %s to bucket: %w", fileName, classify(err))
+	}
+
+	return nil
+}
 
-	_, fileExistsErr := svc.HeadObject(&s3.HeadObjectInput{
-		Bucket: aws.String(bucketName),
+func (b *Bucket) ReadFile(ctx context.Context, fileName string) ([]byte, error) {
+	content, _, err := b.ReadFileVersion(ctx, fileName)
+	return content, err
+}
+
+// ReadFileVersion uses the ETag of the object as its version.
+func (b *Bucket) ReadFileVersion(ctx context.Context, fileName string) ([]byte, string, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	out, err := b.svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: b.name,
+		Key:    aws.String(fileName),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s from bucket: %w", fileName, classify(err))
+	}
+	defer out.Body.Close()
+
+	content, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read body of %s from bucket: %w", fileName, classify(err))
+	}
+
+	return content, aws.StringValue(out.ETag), nil
+}
+
+// ReplaceFile puts the object with If-Match set to the ETag, which S3
+// rejects if the object was written or deleted in the meantime.
+func (b *Bucket) ReplaceFile(ctx context.Context, fileName string, content []byte, version string) (bool, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	_, err := b.svc.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: b.name,
 		Key:    aws.String(fileName),
+		Body:   bytes.NewReader(content),
+	}, func(r *request.Request) {
+		r.HTTPRequest.Header.Set("If-Match", version)
 	})
-	if fileExistsErr != nil {
-		if s3Err, ok := fileExistsErr.(awserr.Error); ok && s3Err.Code() == "NotFound" {
+	if err != nil {
+		if conditionFailed(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to replace %s in bucket: %w", fileName, classify(err))
+	}
+
+	return true, nil
+}
+
+// ClaimFile puts the object with If-None-Match: *, which S3 rejects if the
+// object already exists.
+func (b *Bucket) ClaimFile(ctx context.Context, fileName string, content []byte) (bool, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	_, err := b.svc.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: b.name,
+		Key:    aws.String(fileName),
+		Body:   bytes.NewReader(content),
+	}, func(r *request.Request) {
+		r.HTTPRequest.Header.Set("If-None-Match", "*")
+	})
+	if err != nil {
+		if conditionFailed(err) {
 			return false, nil
-		} else {
-			return false, fmt.Errorf("failed to check if pokemon history exists already: %w", s3Err)
 		}
+		return false, fmt.Errorf("failed to claim %s in bucket: %w", fileName, classify(err))
 	}
 
 	return true, nil
 }
 
-func (b *Bucket) CreateFile(ctx context.Context, fileName string) error {
-	svc := s3.New(createSession())
+func (b *Bucket) DeleteFile(ctx context.Context, fileName string) error {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
 
-	_, err := svc.PutObject(&s3.PutObjectInput{
-		Bucket: aws.String(bucketName),
+	_, err := b.svc.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: b.name,
 		Key:    aws.String(fileName),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to update published pokemon history: %w", err)
+		return fmt.Errorf("failed to delete %s from bucket: %w", fileName, classify(err))
 	}
 
 	return nil
 }
+
+func (b *Bucket) List(ctx context.Context, prefix string) ([]string, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	names := []string{}
+	err := b.svc.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket:    b.name,
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			names = append(names, aws.StringValue(object.Key))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects under %q in bucket: %w", prefix, classify(err))
+	}
+
+	return names, nil
+}
+
+// conditionFailed reports whether a conditional put lost to another write. A
+// conflict means another conditional write to the object is in progress,
+// which will win or has won; a missing key means an If-Match object was
+// deleted.
+func conditionFailed(err error) bool {
+	reqErr, ok := err.(awserr.RequestFailure)
+	if !ok {
+		return false
+	}
+
+	switch reqErr.StatusCode() {
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		return true
+	}
+
+	return reqErr.Code() == s3.ErrCodeNoSuchKey
+}
+
+// classify wraps err with the cloud error matching its cause. A missing
+// bucket is not reported as cloud.ErrNotFound, since callers take that to
+// mean the object has not been written yet.
+func classify(err error) error {
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) {
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("%w: %w", cloud.ErrTransient, err)
+		}
+		return err
+	}
+
+	switch awsErr.Code() {
+	case s3.ErrCodeNoSuchKey:
+		return fmt.Errorf("%w: %w", cloud.ErrNotFound, err)
+	case request.CanceledErrorCode:
+		// only the deadline is worth a retry; a canceled caller is not
+		if errors.Is(awsErr.OrigErr(), context.DeadlineExceeded) {
+			return fmt.Errorf("%w: %w", cloud.ErrTransient, err)
+		}
+		return err
+	case request.ErrCodeRequestError, request.ErrCodeResponseTimeout, "RequestTimeout", "SlowDown":
+		return fmt.Errorf("%w: %w", cloud.ErrTransient, err)
+	}
+
+	var reqErr awserr.RequestFailure
+	if errors.As(err, &reqErr) {
+		switch status := reqErr.StatusCode(); {
+		case status == http.StatusUnauthorized || status == http.StatusForbidden:
+			return fmt.Errorf("%w: %w", cloud.ErrPermission, err)
+		case status == http.StatusRequestTimeout || status == http.StatusTooManyRequests || status >= 500:
+			return fmt.Errorf("%w: %w", cloud.ErrTransient, err)
+		}
+	}
+
+	return err
+}
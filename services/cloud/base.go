@@ -1,8 +1,77 @@
 package cloud
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// Errors of the cloud backends wrap one of these when the cause is known, so
+// callers can tell them apart with errors.Is whatever the backend.
+var (
+	// ErrNotFound is returned by ReadFile when the object does not exist.
+	ErrNotFound = errors.New("object not found")
+
+	// ErrPermission is returned when the credentials are not allowed to
+	// access the bucket or object.
+	ErrPermission = errors.New("permission denied")
+
+	// ErrTransient is returned when the call failed for a reason that may go
+	// away on retry, such as a timeout, throttling or a server error.
+	ErrTransient = errors.New("transient storage error")
+)
 
 type FileBucket interface {
 	FileExists(ctx context.Context, object string) (bool, error)
-	CreateFile(ctx context.Context, object string) error
+	CreateFile(ctx context.Context, object string, content []byte) error
+	ReadFile(ctx context.Context, object string) ([]byte, error)
+
+	// ClaimFile atomically creates the object only if it does not exist yet.
+	// It reports false, without an error, when the object already exists, so
+	// only one of several concurrent callers gets to claim it.
+	ClaimFile(ctx context.Context, object string, content []byte) (bool, error)
+
+	// ReadFileVersion reads the object along with its version, an opaque
+	// token that changes whenever the object is written.
+	ReadFileVersion(ctx context.Context, object string) ([]byte, string, error)
+
+	// ReplaceFile overwrites the object only if it is still at version, as
+	// returned by ReadFileVersion. It reports false, without an error, when
+	// the object has been written or deleted since, so only one of several
+	// concurrent callers gets to replace it.
+	ReplaceFile(ctx context.Context, object string, content []byte, version string) (bool, error)
+
+	// DeleteFile removes the object. Deleting an object that does not exist
+	// is not an error.
+	DeleteFile(ctx context.Context, object string) error
+
+	// List returns the names of the objects directly under prefix, in
+	// lexical order. Objects whose name continues with a "/" after prefix,
+	// like those of a subdirectory, are left out.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// ObjectAttrs are stored along with the content of an object by the backends
+// that support them.
+type ObjectAttrs struct {
+	ContentType string
+
+	// Metadata are custom key-value pairs, e.g. to tell objects apart in the
+	// console without opening them.
+	Metadata map[string]string
+}
+
+// AttrsWriter is implemented by buckets that can store attributes with an
+// object.
+type AttrsWriter interface {
+	CreateFileWithAttrs(ctx context.Context, object string, content []byte, attrs ObjectAttrs) error
+}
+
+// CreateFileWithAttrs writes the object with attrs if bucket supports them,
+// and only its content otherwise.
+func CreateFileWithAttrs(ctx context.Context, bucket FileBucket, object string, content []byte, attrs ObjectAttrs) error {
+	if writer, ok := bucket.(AttrsWriter); ok {
+		return writer.CreateFileWithAttrs(ctx, object, content, attrs)
+	}
+
+	return bucket.CreateFile(ctx, object, content)
 }
@@ -0,0 +1,238 @@
+// Package cloudtest checks that a cloud.FileBucket implementation keeps the
+// contract the bot relies on, so every backend is tested the same way.
+package cloudtest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+)
+
+// RunBucketTests runs the contract tests against buckets made by newBucket,
+// which must return an empty bucket on every call.
+func RunBucketTests(t *testing.T, newBucket func(t *testing.T) cloud.FileBucket) {
+	t.Run("ClaimFile", func(t *testing.T) { testClaimFile(t, newBucket) })
+	t.Run("ReplaceFile", func(t *testing.T) { testReplaceFile(t, newBucket) })
+	t.Run("List", func(t *testing.T) { testList(t, newBucket) })
+	t.Run("ReadFile", func(t *testing.T) { testReadFile(t, newBucket) })
+}
+
+func testClaimFile(t *testing.T, newBucket func(t *testing.T) cloud.FileBucket) {
+	tests := []struct {
+		name        string
+		setup       func(ctx context.Context, bucket cloud.FileBucket) error
+		wantClaimed bool
+		wantContent string
+	}{
+		{
+			name:        "new object",
+			wantClaimed: true,
+			wantContent: "second",
+		},
+		{
+			name: "claim twice",
+			setup: func(ctx context.Context, bucket cloud.FileBucket) error {
+				_, err := bucket.ClaimFile(ctx, "leases/2026-10-18", []byte("first"))
+				return err
+			},
+			wantClaimed: false,
+			wantContent: "first",
+		},
+		{
+			name: "claim after DeleteFile",
+			setup: func(ctx context.Context, bucket cloud.FileBucket) error {
+				if _, err := bucket.ClaimFile(ctx, "leases/2026-10-18", []byte("first")); err != nil {
+					return err
+				}
+				return bucket.DeleteFile(ctx, "leases/2026-10-18")
+			},
+			wantClaimed: true,
+			wantContent: "second",
+		},
+		{
+			name: "object written by CreateFile",
+			setup: func(ctx context.Context, bucket cloud.FileBucket) error {
+				return bucket.CreateFile(ctx, "leases/2026-10-18", []byte("first"))
+			},
+			wantClaimed: false,
+			wantContent: "first",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			bucket := newBucket(t)
+
+			if tt.setup != nil {
+				if err := tt.setup(ctx, bucket); err != nil {
+					t.Fatalf("setup failed: %v", err)
+				}
+			}
+
+			claimed, err := bucket.ClaimFile(ctx, "leases/2026-10-18", []byte("second"))
+			if err != nil {
+				t.Fatalf("ClaimFile failed: %v", err)
+			}
+			if claimed != tt.wantClaimed {
+				t.Errorf("ClaimFile claimed = %v, want %v", claimed, tt.wantClaimed)
+			}
+
+			assertContent(t, ctx, bucket, "leases/2026-10-18", tt.wantContent)
+		})
+	}
+}
+
+func testReplaceFile(t *testing.T, newBucket func(t *testing.T) cloud.FileBucket) {
+	tests := []struct {
+		name         string
+		afterRead    func(ctx context.Context, bucket cloud.FileBucket) error
+		wantReplaced bool
+		wantContent  string
+	}{
+		{
+			name:         "unchanged since read",
+			wantReplaced: true,
+			wantContent:  "replaced",
+		},
+		{
+			name: "written since read",
+			afterRead: func(ctx context.Context, bucket cloud.FileBucket) error {
+				return bucket.CreateFile(ctx, "leases/2026-10-18", []byte("concurrent"))
+			},
+			wantReplaced: false,
+			wantContent:  "concurrent",
+		},
+		{
+			name: "replaced since read",
+			afterRead: func(ctx context.Context, bucket cloud.FileBucket) error {
+				_, version, err := bucket.ReadFileVersion(ctx, "leases/2026-10-18")
+				if err != nil {
+					return err
+				}
+				_, err = bucket.ReplaceFile(ctx, "leases/2026-10-18", []byte("concurrent"), version)
+				return err
+			},
+			wantReplaced: false,
+			wantContent:  "concurrent",
+		},
+		{
+			name: "deleted since read",
+			afterRead: func(ctx context.Context, bucket cloud.FileBucket) error {
+				return bucket.DeleteFile(ctx, "leases/2026-10-18")
+			},
+			wantReplaced: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			bucket := newBucket(t)
+
+			if err := bucket.CreateFile(ctx, "leases/2026-10-18", []byte("stale")); err != nil {
+				t.Fatalf("CreateFile failed: %v", err)
+			}
+			content, version, err := bucket.ReadFileVersion(ctx, "leases/2026-10-18")
+			if err != nil {
+				t.Fatalf("ReadFileVersion failed: %v", err)
+			}
+			if string(content) != "stale" {
+				t.Fatalf("ReadFileVersion content = %q, want %q", content, "stale")
+			}
+
+			if tt.afterRead != nil {
+				if err := tt.afterRead(ctx, bucket); err != nil {
+					t.Fatalf("concurrent write failed: %v", err)
+				}
+			}
+
+			replaced, err := bucket.ReplaceFile(ctx, "leases/2026-10-18", []byte("replaced"), version)
+			if err != nil {
+				t.Fatalf("ReplaceFile failed: %v", err)
+			}
+			if replaced != tt.wantReplaced {
+				t.Errorf("ReplaceFile replaced = %v, want %v", replaced, tt.wantReplaced)
+			}
+
+			if tt.wantContent == "" {
+				if exists, err := bucket.FileExists(ctx, "leases/2026-10-18"); err != nil || exists {
+					t.Errorf("FileExists = %v, %v, want false", exists, err)
+				}
+				return
+			}
+			assertContent(t, ctx, bucket, "leases/2026-10-18", tt.wantContent)
+		})
+	}
+}
+
+func testList(t *testing.T, newBucket func(t *testing.T) cloud.FileBucket) {
+	ctx := context.Background()
+	bucket := newBucket(t)
+
+	for _, object := range []string{
+		"25", "1", "deck.json",
+		"leases/2026-10-18", "leases/pokemon-25",
+		"history/25/20261018T150000Z.json",
+		"pokeapi-cache/abc",
+	} {
+		if err := bucket.CreateFile(ctx, object, []byte("{}")); err != nil {
+			t.Fatalf("CreateFile(%q) failed: %v", object, err)
+		}
+	}
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{prefix: "", want: []string{"1", "25", "deck.json"}},
+		{prefix: "leases/", want: []string{"leases/2026-10-18", "leases/pokemon-25"}},
+		{prefix: "leases/pok", want: []string{"leases/pokemon-25"}},
+		{prefix: "history/", want: []string{}},
+		{prefix: "history/25/", want: []string{"history/25/20261018T150000Z.json"}},
+		{prefix: "missing/", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			names, err := bucket.List(ctx, tt.prefix)
+			if err != nil {
+				t.Fatalf("List failed: %v", err)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("List(%q) = %q, want %q", tt.prefix, names, tt.want)
+			}
+		})
+	}
+}
+
+func testReadFile(t *testing.T, newBucket func(t *testing.T) cloud.FileBucket) {
+	ctx := context.Background()
+	bucket := newBucket(t)
+
+	if _, err := bucket.ReadFile(ctx, "missing"); !errors.Is(err, cloud.ErrNotFound) {
+		t.Errorf("ReadFile of a missing object returned %v, want cloud.ErrNotFound", err)
+	}
+	if _, _, err := bucket.ReadFileVersion(ctx, "missing"); !errors.Is(err, cloud.ErrNotFound) {
+		t.Errorf("ReadFileVersion of a missing object returned %v, want cloud.ErrNotFound", err)
+	}
+
+	if err := bucket.DeleteFile(ctx, "missing"); err != nil {
+		t.Errorf("DeleteFile of a missing object failed: %v", err)
+	}
+}
+
+func assertContent(t *testing.T, ctx context.Context, bucket cloud.FileBucket, object string, want string) {
+	t.Helper()
+
+	content, err := bucket.ReadFile(ctx, object)
+	if err != nil {
+		t.Fatalf("ReadFile(%q) failed: %v", object, err)
+	}
+	if string(content) != want {
+		t.Errorf("ReadFile(%q) = %q, want %q", object, content, want)
+	}
+}
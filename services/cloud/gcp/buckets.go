@@ -2,65 +2,305 @@ package gcp
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
+	"net"
+	"net/http"
 	"os"
+	"regexp"
+	"strconv"
 	"time"
 
 	"cloud.google.com/go/storage"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
 )
 
-var bucket = os.Getenv("GCP_BUCKET")
+// DefaultTimeout bounds each call to GCS when Config.Timeout is not set.
+const DefaultTimeout = 30 * time.Second
 
-type Bucket struct{}
+// Config configures a Bucket.
+type Config struct {
+	// Name is the name of the bucket.
+	Name string
 
-// getMetadata prints all of the object attributes.
-func (b *Bucket) FileExists(ctx context.Context, object string) (bool, error) {
-	client, err := storage.NewClient(ctx)
+	// Timeout bounds each call to GCS, on top of the deadline of its
+	// context. It defaults to DefaultTimeout.
+	Timeout time.Duration
+
+	// Options are passed to the storage client, e.g. to set the credentials
+	// or the endpoint. Without them, application default credentials are
+	// used.
+	Options []option.ClientOption
+}
+
+// ConfigFromEnv reads the bucket name from GCP_BUCKET.
+func ConfigFromEnv() Config {
+	return Config{Name: os.Getenv("GCP_BUCKET")}
+}
+
+// bucketNamePattern follows the GCS bucket naming rules, leaving out the
+// longer dotted names reserved for domain-named buckets.
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,61}[a-z0-9]$`)
+
+// validate checks that the bucket name is valid and, unless options are
+// given, that application default credentials can be found.
+func (c Config) validate(ctx context.Context) error {
+	if c.Name == "" {
+		return fmt.Errorf("GCP_BUCKET is not set")
+	}
+	if !bucketNamePattern.MatchString(c.Name) {
+		return fmt.Errorf("GCP_BUCKET %q is not a valid bucket name", c.Name)
+	}
+
+	if len(c.Options) == 0 {
+		if _, err := google.FindDefaultCredentials(ctx, storage.ScopeReadWrite); err != nil {
+			return fmt.Errorf("no google cloud credentials found: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Bucket is a GCS bucket. It holds one storage client for its lifetime, so
+// it is meant to be created once and shared.
+type Bucket struct {
+	client  *storage.Client
+	bucket  *storage.BucketHandle
+	timeout time.Duration
+}
+
+// NewBucket validates cfg and creates the storage client of the bucket.
+func NewBucket(ctx context.Context, cfg Config) (*Bucket, error) {
+	if err := cfg.validate(ctx); err != nil {
+		return nil, err
+	}
+
+	client, err := storage.NewClient(ctx, cfg.Options...)
 	if err != nil {
-		return false, fmt.Errorf("failed to create google cloud client: %w", err)
+		return nil, fmt.Errorf("failed to create google cloud client: %w", err)
+	}
+
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
 	}
-	defer client.Close()
 
-	o := client.Bucket(bucket).Object(object)
-	_, err = o.Attrs(ctx)
+	return &Bucket{client: client, bucket: client.Bucket(cfg.Name), timeout: timeout}, nil
+}
+
+// Close closes the storage client. The bucket cannot be used afterwards.
+func (b *Bucket) Close() error {
+	return b.client.Close()
+}
+
+func (b *Bucket) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, b.timeout)
+}
+
+func (b *Bucket) FileExists(ctx context.Context, object string) (bool, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	_, err := b.bucket.Object(object).Attrs(ctx)
 	if err != nil {
 		if errors.Is(err, storage.ErrObjectNotExist) {
 			return false, nil
 		}
 
-		return false, fmt.Errorf("failed to get object from bucket: %w", err)
+		return false, fmt.Errorf("failed to get object from bucket: %w", classify(err))
 	}
 	return true, nil
 }
 
-func (b *Bucket) CreateFile(ctx context.Context, object string) error {
-	client, err := storage.NewClient(ctx)
+// CreateFile writes the object straight from content. JSON content is
+// stored as application/json.
+func (b *Bucket) CreateFile(ctx context.Context, object string, content []byte) error {
+	return b.CreateFileWithAttrs(ctx, object, content, cloud.ObjectAttrs{})
+}
+
+// CreateFileWithAttrs writes the object with the content type and custom
+// metadata in attrs. The content type is detected if attrs leaves it empty.
+func (b *Bucket) CreateFileWithAttrs(ctx context.Context, object string, content []byte, attrs cloud.ObjectAttrs) error {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	if err := write(ctx, b.bucket.Object(object), content, attrs); err != nil {
+		return fmt.Errorf("failed to write object %s to bucket: %w", object, classify(err))
+	}
+
+	return nil
+}
+
+func (b *Bucket) ReadFile(ctx context.Context, object string) ([]byte, error) {
+	content, _, err := b.ReadFileVersion(ctx, object)
+	return content, err
+}
+
+// ReadFileVersion uses the generation of the object as its version.
+func (b *Bucket) ReadFileVersion(ctx context.Context, object string) ([]byte, string, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	rc, err := b.bucket.Object(object).NewReader(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to create google cloud client: %w", err)
+		return nil, "", fmt.Errorf("failed to open object %s from bucket: %w", object, classify(err))
 	}
-	defer client.Close()
+	defer rc.Close()
 
-	f, err := os.Create(object)
+	content, err := io.ReadAll(rc)
 	if err != nil {
-		return fmt.Errorf("failed to create file %s to save in bucket: %w", object, err)
+		return nil, "", fmt.Errorf("failed to read object %s from bucket: %w", object, classify(err))
 	}
-	defer f.Close()
-	defer os.Remove(object)
 
-	ctx, cancel := context.WithTimeout(ctx, time.Second*50)
+	return content, strconv.FormatInt(rc.Attrs.Generation, 10), nil
+}
+
+// ReplaceFile writes the object with a GenerationMatch precondition, which
+// GCS rejects if the object was written or deleted in the meantime.
+func (b *Bucket) ReplaceFile(ctx context.Context, object string, content []byte, version string) (bool, error) {
+	generation, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid generation %q of object %s: %w", version, object, err)
+	}
+
+	ctx, cancel := b.withTimeout(ctx)
 	defer cancel()
 
-	o := client.Bucket(bucket).Object(object)
+	if err := write(ctx, b.bucket.Object(object).If(storage.Conditions{GenerationMatch: generation}), content, cloud.ObjectAttrs{}); err != nil {
+		if preconditionFailed(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to replace object %s in bucket: %w", object, classify(err))
+	}
+
+	return true, nil
+}
+
+// ClaimFile writes the object with a DoesNotExist precondition, which GCS
+// rejects if the object was created in the meantime.
+func (b *Bucket) ClaimFile(ctx context.Context, object string, content []byte) (bool, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
 
-	wc := o.NewWriter(ctx)
-	if _, err = io.Copy(wc, f); err != nil {
-		return fmt.Errorf("failed to copy object %s to bucket: %w", bucket, err)
+	if err := write(ctx, b.bucket.Object(object).If(storage.Conditions{DoesNotExist: true}), content, cloud.ObjectAttrs{}); err != nil {
+		if preconditionFailed(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim object %s in bucket: %w", object, classify(err))
 	}
-	if err := wc.Close(); err != nil {
-		return fmt.Errorf("failed to close writer after copying: %w", err)
+
+	return true, nil
+}
+
+func (b *Bucket) DeleteFile(ctx context.Context, object string) error {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	if err := b.bucket.Object(object).Delete(ctx); err != nil && !errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("failed to delete object %s from bucket: %w", object, classify(err))
 	}
 
 	return nil
 }
+
+func (b *Bucket) List(ctx context.Context, prefix string) ([]string, error) {
+	ctx, cancel := b.withTimeout(ctx)
+	defer cancel()
+
+	query := &storage.Query{Prefix: prefix, Delimiter: "/"}
+	if err := query.SetAttrSelection([]string{"Name"}); err != nil {
+		return nil, fmt.Errorf("failed to select object attributes: %w", err)
+	}
+
+	names := []string{}
+	it := b.bucket.Objects(ctx, query)
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects under %q in bucket: %w", prefix, classify(err))
+		}
+
+		// with a delimiter, subdirectories are returned as prefixes
+		if attrs.Name != "" {
+			names = append(names, attrs.Name)
+		}
+	}
+
+	return names, nil
+}
+
+func preconditionFailed(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed
+}
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// write uploads content to the object from memory. GCS checks the content
+// against its CRC32C and rejects the upload if they differ, so a corrupted
+// upload never replaces the object.
+func write(ctx context.Context, handle *storage.ObjectHandle, content []byte, attrs cloud.ObjectAttrs) error {
+	wc := handle.NewWriter(ctx)
+
+	wc.ContentType = attrs.ContentType
+	if wc.ContentType == "" {
+		wc.ContentType = contentType(content)
+	}
+	wc.Metadata = attrs.Metadata
+	wc.CRC32C = crc32.Checksum(content, crc32cTable)
+	wc.SendCRC32C = true
+
+	if _, err := wc.Write(content); err != nil {
+		wc.Close()
+		return err
+	}
+
+	return wc.Close()
+}
+
+// contentType returns application/json for JSON content, which is most of
+// what the bot stores, and the type sniffed from content otherwise.
+func contentType(content []byte) string {
+	if len(content) > 0 && json.Valid(content) {
+		return "application/json"
+	}
+
+	return http.DetectContentType(content)
+}
+
+// classify wraps err with the cloud error matching its cause. A missing
+// bucket is not reported as cloud.ErrNotFound, since callers take that to
+// mean the object has not been written yet.
+func classify(err error) error {
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return fmt.Errorf("%w: %w", cloud.ErrNotFound, err)
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == http.StatusUnauthorized || apiErr.Code == http.StatusForbidden:
+			return fmt.Errorf("%w: %w", cloud.ErrPermission, err)
+		case apiErr.Code == http.StatusRequestTimeout || apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= 500:
+			return fmt.Errorf("%w: %w", cloud.ErrTransient, err)
+		}
+		return err
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", cloud.ErrTransient, err)
+	}
+
+	return err
+}
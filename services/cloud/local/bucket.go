@@ -0,0 +1,289 @@
+package local
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+)
+
+// lockFile serializes writers across processes sharing the directory.
+const lockFile = ".lock"
+
+// Bucket keeps objects as files in a local directory, so the bot can run
+// without a cloud account. Object names may contain slashes, which become
+// subdirectories.
+type Bucket struct {
+	dir string
+}
+
+func NewBucket(dir string) (*Bucket, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create bucket directory %s: %w", dir, err)
+	}
+
+	return &Bucket{dir: dir}, nil
+}
+
+// path maps an object name to its file, rejecting names that would escape
+// the directory.
+func (b *Bucket) path(object string) (string, error) {
+	if object == "" || object == lockFile || filepath.IsAbs(object) {
+		return "", fmt.Errorf("invalid object name %q", object)
+	}
+
+	for _, segment := range strings.Split(object, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", fmt.Errorf("invalid object name %q", object)
+		}
+	}
+
+	return filepath.Join(b.dir, filepath.FromSlash(object)), nil
+}
+
+func (b *Bucket) FileExists(ctx context.Context, object string) (bool, error) {
+	path, err := b.path(object)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check if %s exists: %w", object, err)
+	}
+
+	return true, nil
+}
+
+func (b *Bucket) CreateFile(ctx context.Context, object string, content []byte) error {
+	path, err := b.path(object)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := b.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tmp, err := writeTemp(path, object, content)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save %s: %w", object, err)
+	}
+
+	return nil
+}
+
+func (b *Bucket) ClaimFile(ctx context.Context, object string, content []byte) (bool, error) {
+	path, err := b.path(object)
+	if err != nil {
+		return false, err
+	}
+
+	unlock, err := b.lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	tmp, err := writeTemp(path, object, content)
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp)
+
+	// unlike a rename, a hard link fails if the object exists, so the claim
+	// stays atomic even where the lock is unavailable.
+	if err := os.Link(tmp, path); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to claim %s: %w", object, err)
+	}
+
+	return true, nil
+}
+
+// ReplaceFile compares the version under the lock, so no other writer can
+// change the object between the check and the rename.
+func (b *Bucket) ReplaceFile(ctx context.Context, object string, content []byte, version string) (bool, error) {
+	path, err := b.path(object)
+	if err != nil {
+		return false, err
+	}
+
+	unlock, err := b.lock()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	current, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read %s: %w", object, err)
+	}
+	if contentVersion(current) != version {
+		return false, nil
+	}
+
+	tmp, err := writeTemp(path, object, content)
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp)
+
+	if err := os.Rename(tmp, path); err != nil {
+		return false, fmt.Errorf("failed to save %s: %w", object, err)
+	}
+
+	return true, nil
+}
+
+func (b *Bucket) DeleteFile(ctx context.Context, object string) error {
+	path, err := b.path(object)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := b.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete %s: %w", object, err)
+	}
+
+	return nil
+}
+
+// List reads the directory prefix names, keeping the files whose name starts
+// with the rest of prefix. Lock and temporary files are left out.
+func (b *Bucket) List(ctx context.Context, prefix string) ([]string, error) {
+	dir, namePrefix := "", prefix
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		dir, namePrefix = prefix[:i+1], prefix[i+1:]
+	}
+
+	entries, err := os.ReadDir(filepath.Join(b.dir, filepath.FromSlash(dir)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+	}
+
+	// os.ReadDir sorts by file name
+	names := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || name == lockFile || strings.HasPrefix(name, ".tmp-") || !strings.HasPrefix(name, namePrefix) {
+			continue
+		}
+		names = append(names, dir+name)
+	}
+
+	return names, nil
+}
+
+// writeTemp writes content to a temporary file next to path, so it can be
+// moved into place without a concurrent reader ever seeing a partially
+// written object.
+func writeTemp(path string, object string, content []byte) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create directory of %s: %w", object, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", object, err)
+	}
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write %s: %w", object, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write %s: %w", object, err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write %s: %w", object, err)
+	}
+
+	return tmp.Name(), nil
+}
+
+func (b *Bucket) ReadFile(ctx context.Context, object string) ([]byte, error) {
+	path, err := b.path(object)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, cloud.ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", object, err)
+	}
+
+	return content, nil
+}
+
+// ReadFileVersion uses a hash of the content as the version. Objects are
+// small, and unlike modification times, hashes do not depend on the
+// resolution of the file system's clock.
+func (b *Bucket) ReadFileVersion(ctx context.Context, object string) ([]byte, string, error) {
+	content, err := b.ReadFile(ctx, object)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return content, contentVersion(content), nil
+}
+
+func contentVersion(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// lock takes the directory's write lock, returning the function that
+// releases it.
+func (b *Bucket) lock() (func(), error) {
+	f, err := os.OpenFile(filepath.Join(b.dir, lockFile), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := lockExclusive(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock bucket directory: %w", err)
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,18 @@
+package local
+
+import (
+	"testing"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud/cloudtest"
+)
+
+func TestBucket(t *testing.T) {
+	cloudtest.RunBucketTests(t, func(t *testing.T) cloud.FileBucket {
+		bucket, err := NewBucket(t.TempDir())
+		if err != nil {
+			t.Fatalf("NewBucket failed: %v", err)
+		}
+		return bucket
+	})
+}
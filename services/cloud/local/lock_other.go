@@ -0,0 +1,15 @@
+//go:build !unix
+
+package local
+
+import "os"
+
+// lockExclusive is a no-op where flock is unavailable; writes are still
+// atomic, but concurrent writers are not serialized.
+func lockExclusive(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build unix
+
+package local
+
+import (
+	"os"
+	"syscall"
+)
+
+func lockExclusive(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
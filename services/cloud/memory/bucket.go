@@ -0,0 +1,125 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+)
+
+// Bucket keeps objects in memory. Nothing outlives the process, which makes
+// it suited to tests and trial runs.
+type Bucket struct {
+	mu      sync.Mutex
+	objects map[string]storedObject
+
+	// generation numbers every write, like GCS object generations.
+	generation int64
+}
+
+type storedObject struct {
+	content    []byte
+	generation int64
+}
+
+func NewBucket() *Bucket {
+	return &Bucket{objects: make(map[string]storedObject)}
+}
+
+// put stores a copy of content, so later changes by the caller do not leak
+// into the bucket. b.mu must be held.
+func (b *Bucket) put(name string, content []byte) {
+	b.generation++
+	b.objects[name] = storedObject{content: append([]byte{}, content...), generation: b.generation}
+}
+
+func (b *Bucket) FileExists(ctx context.Context, object string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, ok := b.objects[object]
+	return ok, nil
+}
+
+func (b *Bucket) CreateFile(ctx context.Context, object string, content []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.put(object, content)
+	return nil
+}
+
+func (b *Bucket) ClaimFile(ctx context.Context, object string, content []byte) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.objects[object]; ok {
+		return false, nil
+	}
+
+	b.put(object, content)
+	return true, nil
+}
+
+func (b *Bucket) ReplaceFile(ctx context.Context, object string, content []byte, version string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	current, ok := b.objects[object]
+	if !ok || strconv.FormatInt(current.generation, 10) != version {
+		return false, nil
+	}
+
+	b.put(object, content)
+	return true, nil
+}
+
+func (b *Bucket) DeleteFile(ctx context.Context, object string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.objects, object)
+	return nil
+}
+
+func (b *Bucket) List(ctx context.Context, prefix string) ([]string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	names := []string{}
+	for object := range b.objects {
+		if strings.HasPrefix(object, prefix) && !strings.Contains(object[len(prefix):], "/") {
+			names = append(names, object)
+		}
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (b *Bucket) ReadFile(ctx context.Context, object string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stored, ok := b.objects[object]
+	if !ok {
+		return nil, cloud.ErrNotFound
+	}
+
+	return append([]byte{}, stored.content...), nil
+}
+
+func (b *Bucket) ReadFileVersion(ctx context.Context, object string) ([]byte, string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stored, ok := b.objects[object]
+	if !ok {
+		return nil, "", cloud.ErrNotFound
+	}
+
+	return append([]byte{}, stored.content...), strconv.FormatInt(stored.generation, 10), nil
+}
@@ -0,0 +1,14 @@
+package memory
+
+import (
+	"testing"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud/cloudtest"
+)
+
+func TestBucket(t *testing.T) {
+	cloudtest.RunBucketTests(t, func(t *testing.T) cloud.FileBucket {
+		return NewBucket()
+	})
+}
@@ -0,0 +1,83 @@
+package pokemon
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// proseMarkup matches the cross-reference markup used in PokeAPI prose, e.g.
+// "[HP]{mechanic:hp}" or "[]{move:ember}".
+var proseMarkup = regexp.MustCompile(`\[([^\]]*)\]\{[^:}]*:([^}]*)\}`)
+
+// cleanProse replaces prose markup with its label, or with the name of the
+// referenced resource when the label is empty.
+func cleanProse(text string) string {
+	text = proseMarkup.ReplaceAllStringFunc(text, func(markup string) string {
+		groups := proseMarkup.FindStringSubmatch(markup)
+		if groups[1] != "" {
+			return groups[1]
+		}
+		return strings.ReplaceAll(groups[2], "-", " ")
+	})
+
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// getShortEffect returns the English short effect of an ability.
+func getShortEffect(ability RespAbility) (string, error) {
+	for _, entry := range ability.EffectEntries {
+		if entry.Language.Name == "en" && entry.ShortEffect != "" {
+			return cleanProse(entry.ShortEffect), nil
+		}
+	}
+
+	return "", fmt.Errorf("English short effect not found for ability %s", ability.Name)
+}
+
+// getAbilities renders one line per ability in slot order, marking the hidden
+// ability. The effect text is left out for abilities it cannot be fetched
+// for rather than dropping the whole section.
+func getAbilities(ctx context.Context, api PokeAPI, pokemon RespPokemon) (string, error) {
+	if len(pokemon.Abilities) == 0 {
+		return "", nil
+	}
+
+	abilities := append([]Abilities{}, pokemon.Abilities...)
+	sort.Slice(abilities, func(i, j int) bool {
+		return abilities[i].Slot < abilities[j].Slot
+	})
+
+	lines := []string{"Abilities:"}
+	var errs []string
+
+	for _, ability := range abilities {
+		line := formatName(ability.Ability.Name)
+		if ability.IsHidden {
+			line += " (hidden)"
+		}
+
+		details, err := api.GetAbility(ctx, ability.Ability.Name)
+		if err == nil {
+			var effect string
+			effect, err = getShortEffect(details)
+			if err == nil {
+				line += ": " + effect
+			}
+		}
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+
+		lines = append(lines, "• "+line)
+	}
+
+	var err error
+	if len(errs) > 0 {
+		err = fmt.Errorf("failed to get effect of some abilities: %s", strings.Join(errs, "; "))
+	}
+
+	return strings.Join(lines, "\n"), err
+}
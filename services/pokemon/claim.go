@@ -0,0 +1,236 @@
+package pokemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+	"github.com/rs/zerolog"
+)
+
+// leasePrefix holds one lease object per calendar day of the schedule and
+// per pokemon published.
+const leasePrefix = "leases/"
+
+// defaultLeaseTTL is how long a lease blocks its slot when the invocation
+// holding it neither publishes nor releases it, e.g. because it timed out or
+// was killed. It is well above the longest function timeout, so a lease is
+// never taken over from an invocation that is still posting.
+const defaultLeaseTTL = 2 * time.Hour
+
+// lease is the content of a lease object.
+type lease struct {
+	Date      string    `json:"date,omitempty"`
+	PokemonID int       `json:"pokemon_id,omitempty"`
+	ClaimedAt time.Time `json:"claimed_at"`
+	Holder    string    `json:"holder"`
+
+	// PublishedAt is set once the post is published. A published lease is
+	// never taken over, however old it is.
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	PostURI     string     `json:"post_uri,omitempty"`
+}
+
+func dayLeaseObject(date string) string {
+	return leasePrefix + date
+}
+
+func pokemonLeaseObject(pokemonID int) string {
+	return fmt.Sprintf("%spokemon-%d", leasePrefix, pokemonID)
+}
+
+// leaseHolder identifies the invocation holding a claim, to help tell
+// overlapping invocations apart in the bucket.
+func leaseHolder() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+
+	return fmt.Sprintf("%s/%d", host, os.Getpid())
+}
+
+// leaseTTLFromEnv reads LEASE_TTL, e.g. "90m", falling back to
+// defaultLeaseTTL.
+func leaseTTLFromEnv() (time.Duration, error) {
+	raw := os.Getenv("LEASE_TTL")
+	if raw == "" {
+		return defaultLeaseTTL, nil
+	}
+
+	ttl, err := time.ParseDuration(raw)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("LEASE_TTL must be a positive duration, e.g. 90m, got %q", raw)
+	}
+
+	return ttl, nil
+}
+
+// heldLease is a lease object claimed by this invocation.
+type heldLease struct {
+	object string
+	lease  lease
+}
+
+// claims are the leases claimed by a publish. They are released if the
+// publish fails before anything is posted, so a retry can claim them again,
+// and marked published once the post is up.
+type claims struct {
+	bucket cloud.FileBucket
+	ttl    time.Duration
+	held   []heldLease
+}
+
+func newClaims(bucket cloud.FileBucket) (*claims, error) {
+	ttl, err := leaseTTLFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return &claims{bucket: bucket, ttl: ttl}, nil
+}
+
+// claimDay claims the day of the schedule, so that overlapping or retried
+// invocations publish the pokemon of the day only once.
+func (c *claims) claimDay(ctx context.Context, now time.Time, date string) error {
+	return c.claim(ctx, now, dayLeaseObject(date), lease{Date: date},
+		fmt.Sprintf("the pokemon of the day for %s", date), false)
+}
+
+// claimPokemon claims the planned pokemon, so that a scheduled run and a
+// manual publish never post it at the same time. republish lets a lease
+// marked published be taken over, since a new round of the selector or a
+// forced override publishes pokemon that have been published before.
+func (c *claims) claimPokemon(ctx context.Context, now time.Time, planned plan, republish bool) error {
+	return c.claim(ctx, now, pokemonLeaseObject(planned.PokemonID), lease{PokemonID: planned.PokemonID},
+		fmt.Sprintf("pokemon #%d", planned.PokemonID), republish)
+}
+
+// claim creates the lease object, or takes it over if the invocation holding
+// it let it go stale, or if it is published and republish is set. what
+// describes the slot in errors.
+func (c *claims) claim(ctx context.Context, now time.Time, object string, l lease, what string, republish bool) error {
+	l.ClaimedAt = now.UTC()
+	l.Holder = leaseHolder()
+
+	content, err := json.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("failed to encode lease of %s: %w", what, err)
+	}
+
+	claimed, err := c.bucket.ClaimFile(ctx, object, content)
+	if err != nil {
+		return fmt.Errorf("failed to claim %s: %w", what, err)
+	}
+	if !claimed {
+		if err := c.takeOver(ctx, now, object, content, what, republish); err != nil {
+			return err
+		}
+	}
+
+	c.held = append(c.held, heldLease{object: object, lease: l})
+	return nil
+}
+
+// takeOver replaces the existing lease object with content if the lease is
+// stale, or published and republish is set. The replace is conditioned on
+// the version that was read, so of several invocations finding the same
+// lease only one takes it over.
+func (c *claims) takeOver(ctx context.Context, now time.Time, object string, content []byte, what string, republish bool) error {
+	existing, version, err := c.bucket.ReadFileVersion(ctx, object)
+	if errors.Is(err, cloud.ErrNotFound) {
+		// the lease was released in the meantime
+		claimed, err := c.bucket.ClaimFile(ctx, object, content)
+		if err != nil {
+			return fmt.Errorf("failed to claim %s: %w", what, err)
+		}
+		if !claimed {
+			return fmt.Errorf("%s was claimed by another invocation in the meantime", what)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the lease of %s: %w", what, err)
+	}
+
+	var held lease
+	if err := json.Unmarshal(existing, &held); err != nil {
+		return fmt.Errorf("the lease of %s is not a valid JSON; delete %s to retry: %w", what, object, err)
+	}
+
+	if held.PublishedAt != nil && !republish {
+		return fmt.Errorf("%s has already been published at %s in %s", what, held.PublishedAt.Format(time.RFC3339), held.PostURI)
+	}
+
+	age := now.Sub(held.ClaimedAt)
+	if held.PublishedAt == nil && age < c.ttl {
+		return fmt.Errorf("%s is being published by %s since %s; its lease goes stale in %s",
+			what, held.Holder, held.ClaimedAt.Format(time.RFC3339), (c.ttl - age).Round(time.Second))
+	}
+
+	replaced, err := c.bucket.ReplaceFile(ctx, object, content, version)
+	if err != nil {
+		return fmt.Errorf("failed to take over the lease of %s: %w", what, err)
+	}
+	if !replaced {
+		return fmt.Errorf("the lease of %s was taken over by another invocation", what)
+	}
+	if held.PublishedAt != nil {
+		return nil
+	}
+
+	logger := zerolog.New(os.Stdout)
+	logger.Warn().Msgf("took over the stale lease of %s held by %s since %s", what, held.Holder, held.ClaimedAt.Format(time.RFC3339))
+
+	return nil
+}
+
+// markPublished records the post in the held leases, so they can never be
+// taken over and a retry reports where the pokemon was published.
+func (c *claims) markPublished(ctx context.Context, now time.Time, postURI string) error {
+	publishedAt := now.UTC()
+
+	var errs []error
+	for i := range c.held {
+		c.held[i].lease.PublishedAt = &publishedAt
+		c.held[i].lease.PostURI = postURI
+
+		content, err := json.Marshal(c.held[i].lease)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := c.bucket.CreateFile(ctx, c.held[i].object, content); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to mark leases published; they may be taken over once stale: %v", errs)
+	}
+
+	return nil
+}
+
+// release deletes the held leases. Errors are returned together, since a
+// lease that cannot be released only blocks its slot until it goes stale or
+// is deleted by hand.
+func (c *claims) release(ctx context.Context) error {
+	var errs []error
+	for _, held := range c.held {
+		if err := c.bucket.DeleteFile(ctx, held.object); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	c.held = nil
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to release claims; delete them from the bucket to retry: %v", errs)
+	}
+
+	return nil
+}
@@ -0,0 +1,449 @@
+package pokemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cache"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultBaseURL   = "https://pokeapi.co/api/v2"
+	defaultTimeout   = 30 * time.Second
+	defaultUserAgent = "random-pokemon-publisher"
+	defaultCacheTTL  = 7 * 24 * time.Hour
+	defaultCacheDir  = ".pokeapi-cache"
+)
+
+// supported values of ClientConfig.Cache
+const (
+	CacheNone   = ""
+	CacheDir    = "dir"
+	CacheBucket = "bucket"
+)
+
+// PokeAPI is the set of PokeAPI lookups the bot needs to build a post.
+type PokeAPI interface {
+	GetPokemon(ctx context.Context, id int) (RespPokemon, error)
+	GetPokemonByName(ctx context.Context, name string) (RespPokemon, error)
+	GetSpecies(ctx context.Context, id int) (RespPokemonSpecies, error)
+	GetEvolutionChain(ctx context.Context, id int) (RespEvolutionChain, error)
+	GetAbility(ctx context.Context, name string) (RespAbility, error)
+	GetPokemonForm(ctx context.Context, name string) (RespPokemonForm, error)
+	GetType(ctx context.Context, name string) (RespType, error)
+	GetSpeciesCount(ctx context.Context) (int, error)
+	GetGeneration(ctx context.Context, id int) (RespGeneration, error)
+	GetSprite(ctx context.Context, url string) ([]byte, error)
+}
+
+// ClientConfig configures the HTTP implementation of PokeAPI.
+type ClientConfig struct {
+	BaseURL   string
+	Timeout   time.Duration
+	UserAgent string
+
+	// Cache selects where raw responses are cached: CacheNone, CacheDir or
+	// CacheBucket.
+	Cache    string
+	CacheDir string
+	CacheTTL time.Duration
+
+	// DumpDir, when set, switches to the offline client backed by the
+	// PokeAPI CSV data dump in that directory, with sprites read from
+	// SpriteDir.
+	DumpDir   string
+	SpriteDir string
+}
+
+// ClientConfigFromEnv reads the client configuration from POKEAPI_BASE_URL,
+// POKEAPI_TIMEOUT and POKEAPI_USER_AGENT, falling back to the public PokeAPI.
+// Caching is configured with POKEAPI_CACHE, POKEAPI_CACHE_DIR and
+// POKEAPI_CACHE_TTL, and offline mode with POKEAPI_DUMP_DIR and
+// POKEAPI_SPRITE_DIR.
+func ClientConfigFromEnv() (ClientConfig, error) {
+	cfg := ClientConfig{
+		BaseURL:   defaultBaseURL,
+		Timeout:   defaultTimeout,
+		UserAgent: defaultUserAgent,
+		Cache:     os.Getenv("POKEAPI_CACHE"),
+		CacheDir:  defaultCacheDir,
+		CacheTTL:  defaultCacheTTL,
+		DumpDir:   os.Getenv("POKEAPI_DUMP_DIR"),
+		SpriteDir: os.Getenv("POKEAPI_SPRITE_DIR"),
+	}
+
+	if baseURL := os.Getenv("POKEAPI_BASE_URL"); baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+
+	if timeout := os.Getenv("POKEAPI_TIMEOUT"); timeout != "" {
+		parsed, err := time.ParseDuration(timeout)
+		if err != nil {
+			return cfg, fmt.Errorf("POKEAPI_TIMEOUT is not a valid duration: %w", err)
+		}
+		cfg.Timeout = parsed
+	}
+
+	if userAgent := os.Getenv("POKEAPI_USER_AGENT"); userAgent != "" {
+		cfg.UserAgent = userAgent
+	}
+
+	switch cfg.Cache {
+	case CacheNone, CacheDir, CacheBucket:
+	default:
+		return cfg, fmt.Errorf("POKEAPI_CACHE must be one of %q or %q, got %q", CacheDir, CacheBucket, cfg.Cache)
+	}
+
+	if cacheDir := os.Getenv("POKEAPI_CACHE_DIR"); cacheDir != "" {
+		cfg.CacheDir = cacheDir
+	}
+
+	if cacheTTL := os.Getenv("POKEAPI_CACHE_TTL"); cacheTTL != "" {
+		parsed, err := time.ParseDuration(cacheTTL)
+		if err != nil {
+			return cfg, fmt.Errorf("POKEAPI_CACHE_TTL is not a valid duration: %w", err)
+		}
+		cfg.CacheTTL = parsed
+	}
+
+	if cfg.DumpDir != "" && cfg.SpriteDir == "" {
+		return cfg, fmt.Errorf("POKEAPI_SPRITE_DIR is required when POKEAPI_DUMP_DIR is set")
+	}
+
+	return cfg, nil
+}
+
+// HTTPClient is the default PokeAPI implementation backed by a REST API.
+type HTTPClient struct {
+	client   *resty.Client
+	baseURL  string
+	cache    cache.Store
+	cacheTTL time.Duration
+	logger   zerolog.Logger
+
+	// cacheFailed is set once writing to the cache fails, so a cache that
+	// cannot be written is reported once instead of on every request.
+	cacheFailed atomic.Bool
+}
+
+// NewHTTPClient creates a client for the configured PokeAPI. store may be nil,
+// in which case every lookup goes to the API.
+func NewHTTPClient(cfg ClientConfig, store cache.Store) *HTTPClient {
+	client := resty.New().
+		SetBaseURL(cfg.BaseURL).
+		SetTimeout(cfg.Timeout).
+		SetHeader("User-Agent", cfg.UserAgent)
+
+	return &HTTPClient{
+		client:   client,
+		baseURL:  strings.TrimSuffix(cfg.BaseURL, "/"),
+		cache:    store,
+		cacheTTL: cfg.CacheTTL,
+		logger:   zerolog.New(os.Stdout),
+	}
+}
+
+// get returns the body of the resource at path, serving it from the cache
+// when possible. Stale entries are revalidated with their ETag and
+// Last-Modified validators, and are served as-is if the API is unreachable.
+func (c *HTTPClient) get(ctx context.Context, path string) ([]byte, error) {
+	url := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		url = c.baseURL + "/" + strings.TrimPrefix(path, "/")
+	}
+
+	var cached cache.Entry
+	hasCached := false
+	if c.cache != nil {
+		entry, err := c.cache.Get(ctx, url)
+		switch {
+		case err == nil:
+			cached = entry
+			hasCached = true
+		case !errors.Is(err, cache.ErrMiss):
+			c.logger.Err(err).Msgf("failed to read cached response for %s", url)
+		}
+	}
+
+	if hasCached && cached.Fresh(c.cacheTTL, time.Now()) {
+		return cached.Body, nil
+	}
+
+	req := c.client.R().SetContext(ctx)
+	if hasCached {
+		if cached.ETag != "" {
+			req.SetHeader("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.SetHeader("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := req.Get(url)
+	if err != nil || resp.StatusCode() >= http.StatusInternalServerError {
+		if hasCached {
+			c.logger.Warn().Msgf("PokeAPI unavailable; serving stale cached response for %s", url)
+			return cached.Body, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to make request to %s: %w", url, err)
+		}
+		return nil, fmt.Errorf("received status %d from %s", resp.StatusCode(), url)
+	}
+
+	if hasCached && resp.StatusCode() == http.StatusNotModified {
+		cached.FetchedAt = time.Now()
+		c.store(ctx, cached)
+		return cached.Body, nil
+	}
+
+	if resp.IsError() {
+		return nil, fmt.Errorf("received status %d from %s", resp.StatusCode(), url)
+	}
+
+	if c.cache != nil {
+		c.store(ctx, cache.Entry{
+			URL:          url,
+			Body:         resp.Body(),
+			ETag:         resp.Header().Get("ETag"),
+			LastModified: resp.Header().Get("Last-Modified"),
+			FetchedAt:    time.Now(),
+		})
+	}
+
+	return resp.Body(), nil
+}
+
+// store saves entry in the cache. Failing to cache is not fatal, the
+// response is simply fetched again next time, but writes are given up for
+// the rest of the run after the first failure.
+func (c *HTTPClient) store(ctx context.Context, entry cache.Entry) {
+	if c.cacheFailed.Load() {
+		return
+	}
+
+	if err := c.cache.Put(ctx, entry); err != nil {
+		c.cacheFailed.Store(true)
+		c.logger.Err(err).Msgf("failed to cache response for %s; the PokeAPI cache is not writable, so nothing more is cached this run", entry.URL)
+	}
+}
+
+func (c *HTTPClient) GetPokemon(ctx context.Context, id int) (RespPokemon, error) {
+	return c.getPokemon(ctx, fmt.Sprintf("pokemon/%d", id))
+}
+
+// namePattern matches the names PokeAPI uses in its paths, e.g. "mr-mime".
+// Names are checked against it before they are put in a path, so user input
+// cannot reach another endpoint or the query string.
+var namePattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+func validName(kind string, name string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("%q is not a valid %s name; use lowercase letters, digits and dashes, e.g. mr-mime", name, kind)
+	}
+
+	return nil
+}
+
+func (c *HTTPClient) GetPokemonByName(ctx context.Context, name string) (RespPokemon, error) {
+	if err := validName("pokemon", name); err != nil {
+		return RespPokemon{}, err
+	}
+
+	return c.getPokemon(ctx, fmt.Sprintf("pokemon/%s", name))
+}
+
+func (c *HTTPClient) getPokemon(ctx context.Context, path string) (RespPokemon, error) {
+	var pokemon RespPokemon
+
+	body, err := c.get(ctx, path)
+	if err != nil {
+		return pokemon, fmt.Errorf("failed to fetch pokemon: %w", err)
+	}
+
+	unmarshalErr := json.Unmarshal(body, &pokemon)
+	if unmarshalErr != nil {
+		return pokemon, fmt.Errorf("fetched GET pokemon response is not a valid JSON: %w", unmarshalErr)
+	}
+
+	if reflect.ValueOf(pokemon).IsZero() {
+		return pokemon, fmt.Errorf("could not populate pokemon data from response")
+	}
+
+	return pokemon, nil
+}
+
+func (c *HTTPClient) GetSpecies(ctx context.Context, id int) (RespPokemonSpecies, error) {
+	var species RespPokemonSpecies
+
+	body, err := c.get(ctx, fmt.Sprintf("pokemon-species/%d", id))
+	if err != nil {
+		return species, fmt.Errorf("failed to fetch pokemon species: %w", err)
+	}
+
+	unmarshalErr := json.Unmarshal(body, &species)
+	if unmarshalErr != nil {
+		return species, fmt.Errorf("fetched GET pokemon species response is not a valid JSON: %w", unmarshalErr)
+	}
+
+	if reflect.ValueOf(species).IsZero() {
+		return species, fmt.Errorf("could not populate species from response")
+	}
+
+	return species, nil
+}
+
+func (c *HTTPClient) GetEvolutionChain(ctx context.Context, id int) (RespEvolutionChain, error) {
+	var chain RespEvolutionChain
+
+	body, err := c.get(ctx, fmt.Sprintf("evolution-chain/%d", id))
+	if err != nil {
+		return chain, fmt.Errorf("failed to fetch evolution chain: %w", err)
+	}
+
+	unmarshalErr := json.Unmarshal(body, &chain)
+	if unmarshalErr != nil {
+		return chain, fmt.Errorf("fetched GET evolution chain response is not a valid JSON: %w", unmarshalErr)
+	}
+
+	if reflect.ValueOf(chain).IsZero() {
+		return chain, fmt.Errorf("could not populate evolution chain from response")
+	}
+
+	return chain, nil
+}
+
+func (c *HTTPClient) GetAbility(ctx context.Context, name string) (RespAbility, error) {
+	var ability RespAbility
+
+	if err := validName("ability", name); err != nil {
+		return ability, err
+	}
+
+	body, err := c.get(ctx, fmt.Sprintf("ability/%s", name))
+	if err != nil {
+		return ability, fmt.Errorf("failed to fetch ability: %w", err)
+	}
+
+	unmarshalErr := json.Unmarshal(body, &ability)
+	if unmarshalErr != nil {
+		return ability, fmt.Errorf("fetched GET ability response is not a valid JSON: %w", unmarshalErr)
+	}
+
+	if reflect.ValueOf(ability).IsZero() {
+		return ability, fmt.Errorf("could not populate ability from response")
+	}
+
+	return ability, nil
+}
+
+func (c *HTTPClient) GetPokemonForm(ctx context.Context, name string) (RespPokemonForm, error) {
+	var form RespPokemonForm
+
+	if err := validName("pokemon form", name); err != nil {
+		return form, err
+	}
+
+	body, err := c.get(ctx, fmt.Sprintf("pokemon-form/%s", name))
+	if err != nil {
+		return form, fmt.Errorf("failed to fetch pokemon form: %w", err)
+	}
+
+	unmarshalErr := json.Unmarshal(body, &form)
+	if unmarshalErr != nil {
+		return form, fmt.Errorf("fetched GET pokemon form response is not a valid JSON: %w", unmarshalErr)
+	}
+
+	if reflect.ValueOf(form).IsZero() {
+		return form, fmt.Errorf("could not populate pokemon form from response")
+	}
+
+	return form, nil
+}
+
+func (c *HTTPClient) GetType(ctx context.Context, name string) (RespType, error) {
+	var pokemonType RespType
+
+	if err := validName("type", name); err != nil {
+		return pokemonType, err
+	}
+
+	body, err := c.get(ctx, fmt.Sprintf("type/%s", name))
+	if err != nil {
+		return pokemonType, fmt.Errorf("failed to fetch type: %w", err)
+	}
+
+	unmarshalErr := json.Unmarshal(body, &pokemonType)
+	if unmarshalErr != nil {
+		return pokemonType, fmt.Errorf("fetched GET type response is not a valid JSON: %w", unmarshalErr)
+	}
+
+	if reflect.ValueOf(pokemonType).IsZero() {
+		return pokemonType, fmt.Errorf("could not populate type from response")
+	}
+
+	return pokemonType, nil
+}
+
+// GetSpeciesCount returns the number of species in the national dex.
+func (c *HTTPClient) GetSpeciesCount(ctx context.Context) (int, error) {
+	var list RespSpeciesList
+
+	body, err := c.get(ctx, "pokemon-species?limit=0")
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch species count: %w", err)
+	}
+
+	unmarshalErr := json.Unmarshal(body, &list)
+	if unmarshalErr != nil {
+		return 0, fmt.Errorf("fetched GET pokemon species list response is not a valid JSON: %w", unmarshalErr)
+	}
+
+	if list.Count == 0 {
+		return 0, fmt.Errorf("could not populate species count from response")
+	}
+
+	return list.Count, nil
+}
+
+func (c *HTTPClient) GetGeneration(ctx context.Context, id int) (RespGeneration, error) {
+	var generation RespGeneration
+
+	body, err := c.get(ctx, fmt.Sprintf("generation/%d", id))
+	if err != nil {
+		return generation, fmt.Errorf("failed to fetch generation: %w", err)
+	}
+
+	unmarshalErr := json.Unmarshal(body, &generation)
+	if unmarshalErr != nil {
+		return generation, fmt.Errorf("fetched GET generation response is not a valid JSON: %w", unmarshalErr)
+	}
+
+	if reflect.ValueOf(generation).IsZero() {
+		return generation, fmt.Errorf("could not populate generation from response")
+	}
+
+	return generation, nil
+}
+
+// GetSprite downloads the image at url. Sprite URLs returned by PokeAPI are
+// absolute, so the configured base URL only applies to relative paths.
+func (c *HTTPClient) GetSprite(ctx context.Context, url string) ([]byte, error) {
+	sprite, err := c.get(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sprite for pokemon: %w", err)
+	}
+
+	return sprite, nil
+}
@@ -0,0 +1,157 @@
+package pokemon
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/exp/rand"
+)
+
+const deckObject = "deck.json"
+
+// deck is a shuffled permutation of the eligible species that is walked
+// through one publish at a time, so every species is published exactly once
+// per season. It is persisted in the history bucket between runs.
+type deck struct {
+	Season   int   `json:"season"`
+	Order    []int `json:"order"`
+	Position int   `json:"position"`
+}
+
+// newDeck shuffles ids into the deck for a season.
+func newDeck(season int, ids []int, rng *rand.Rand) deck {
+	order := append([]int{}, ids...)
+	rng.Shuffle(len(order), func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+
+	return deck{Season: season, Order: order}
+}
+
+// draw moves the next species that is still eligible to the current position
+// of the deck and returns it without consuming it, so a failed publish is
+// retried on the next run. Species that are not eligible right now, e.g.
+// because they are blocked for a while, are passed over but stay in the deck
+// for later in the season. It returns false once no eligible species are
+// left.
+func (d *deck) draw(eligible map[int]bool) (int, bool) {
+	for i := d.Position; i < len(d.Order); i++ {
+		if eligible[d.Order[i]] {
+			d.Order[i], d.Order[d.Position] = d.Order[d.Position], d.Order[i]
+			return d.Order[d.Position], true
+		}
+	}
+
+	return 0, false
+}
+
+// progress reports how far into the season the deck is, counting the current
+// draw.
+func (d deck) progress() string {
+	return fmt.Sprintf("season %d, %d/%d", d.Season, d.Position+1, len(d.Order))
+}
+
+// selection returns id as drawn at the current position of the deck.
+func (d deck) selection(id int) Selection {
+	return Selection{SpeciesID: id, Progress: d.progress(), Season: d.Season}
+}
+
+// deckSelector is the default Selector, drawing species from a persisted
+// shuffled deck.
+type deckSelector struct {
+	state selectionState
+	deck  deck
+}
+
+// load reads the persisted deck, creating the first one if there is none.
+func (s *deckSelector) load(ctx context.Context, eligible []int, rng *rand.Rand) error {
+	s.deck = deck{}
+	found, err := s.state.readState(ctx, deckObject, &s.deck)
+	if err != nil {
+		return fmt.Errorf("failed to read deck: %w", err)
+	}
+
+	if !found {
+		s.deck, err = firstDeck(ctx, s.state, eligible, rng)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *deckSelector) Next(ctx context.Context, day time.Time, eligible []int, rng *rand.Rand) (Selection, error) {
+	if err := s.load(ctx, eligible, rng); err != nil {
+		return Selection{}, err
+	}
+
+	eligibleSet := make(map[int]bool, len(eligible))
+	for _, id := range eligible {
+		eligibleSet[id] = true
+	}
+
+	if id, ok := s.deck.draw(eligibleSet); ok {
+		return s.deck.selection(id), nil
+	}
+
+	s.deck = newDeck(s.deck.Season+1, eligible, rng)
+	if id, ok := s.deck.draw(eligibleSet); ok {
+		return s.deck.selection(id), nil
+	}
+
+	return Selection{}, fmt.Errorf("season %d has no eligible species", s.deck.Season)
+}
+
+// available returns the eligible species still left in the deck loaded by
+// Next.
+func (s *deckSelector) available(ctx context.Context, eligible []int) ([]int, error) {
+	remaining := make(map[int]bool, len(s.deck.Order)-s.deck.Position)
+	for _, id := range s.deck.Order[s.deck.Position:] {
+		remaining[id] = true
+	}
+
+	ids := []int{}
+	for _, id := range eligible {
+		if remaining[id] {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// take moves id to the current position of the deck so that committing the
+// selection consumes it, keeping the rest of the deck in order.
+func (s *deckSelector) take(id int) Selection {
+	for i := s.deck.Position; i < len(s.deck.Order); i++ {
+		if s.deck.Order[i] == id {
+			s.deck.Order[i], s.deck.Order[s.deck.Position] = s.deck.Order[s.deck.Position], s.deck.Order[i]
+			break
+		}
+	}
+
+	return s.deck.selection(id)
+}
+
+func (s *deckSelector) Commit(ctx context.Context, selection Selection) error {
+	s.deck.Position++
+
+	if err := s.state.writeState(ctx, deckObject, s.deck); err != nil {
+		return fmt.Errorf("failed to save deck: %w", err)
+	}
+
+	return nil
+}
+
+// firstDeck builds the deck of the first season. Species published before
+// decks were introduced are left out so they are not published twice.
+func firstDeck(ctx context.Context, state selectionState, eligible []int, rng *rand.Rand) (deck, error) {
+	unpublished, err := unpublishedSpecies(ctx, state, eligible)
+	if err != nil {
+		return deck{}, err
+	}
+
+	return newDeck(1, unpublished, rng), nil
+}
@@ -0,0 +1,108 @@
+package pokemon
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeckDraw(t *testing.T) {
+	tests := []struct {
+		name      string
+		deck      deck
+		eligible  []int
+		wantID    int
+		wantOK    bool
+		wantOrder []int
+	}{
+		{
+			name:      "next species eligible",
+			deck:      deck{Order: []int{1, 2, 3}, Position: 1},
+			eligible:  []int{1, 2, 3},
+			wantID:    2,
+			wantOK:    true,
+			wantOrder: []int{1, 2, 3},
+		},
+		{
+			name:      "blocked species stays in the deck",
+			deck:      deck{Order: []int{1, 2, 3, 4}, Position: 1},
+			eligible:  []int{1, 3, 4},
+			wantID:    3,
+			wantOK:    true,
+			wantOrder: []int{1, 3, 2, 4},
+		},
+		{
+			name:      "several species blocked",
+			deck:      deck{Order: []int{1, 2, 3, 4}},
+			eligible:  []int{4},
+			wantID:    4,
+			wantOK:    true,
+			wantOrder: []int{4, 2, 3, 1},
+		},
+		{
+			name:      "only blocked species left",
+			deck:      deck{Order: []int{1, 2, 3}, Position: 1},
+			eligible:  []int{1},
+			wantOK:    false,
+			wantOrder: []int{1, 2, 3},
+		},
+		{
+			name:      "deck exhausted",
+			deck:      deck{Order: []int{1, 2}, Position: 2},
+			eligible:  []int{1, 2},
+			wantOK:    false,
+			wantOrder: []int{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eligible := make(map[int]bool)
+			for _, id := range tt.eligible {
+				eligible[id] = true
+			}
+
+			position := tt.deck.Position
+			id, ok := tt.deck.draw(eligible)
+			if id != tt.wantID || ok != tt.wantOK {
+				t.Errorf("draw = %d, %v, want %d, %v", id, ok, tt.wantID, tt.wantOK)
+			}
+			if tt.deck.Position != position {
+				t.Errorf("draw moved the position from %d to %d", position, tt.deck.Position)
+			}
+			if !reflect.DeepEqual(tt.deck.Order, tt.wantOrder) {
+				t.Errorf("order = %v, want %v", tt.deck.Order, tt.wantOrder)
+			}
+		})
+	}
+}
+
+// TestDeckKeepsBlockedSpecies blocks a species for part of a season and
+// checks that it is still published before the season ends.
+func TestDeckKeepsBlockedSpecies(t *testing.T) {
+	d := deck{Season: 1, Order: []int{1, 2, 3, 4}}
+	blocked := map[int]bool{1: true, 2: true}
+
+	published := []int{}
+	for len(published) < 4 {
+		eligible := map[int]bool{}
+		for _, id := range d.Order {
+			if !blocked[id] {
+				eligible[id] = true
+			}
+		}
+
+		id, ok := d.draw(eligible)
+		if !ok {
+			t.Fatalf("deck ran out after publishing %v", published)
+		}
+		published = append(published, id)
+		d.Position++
+
+		// the block is lifted after the first publish
+		blocked = map[int]bool{}
+	}
+
+	if !reflect.DeepEqual(published, []int{3, 2, 1, 4}) {
+		t.Errorf("published %v, want [3 2 1 4]", published)
+	}
+}
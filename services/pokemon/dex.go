@@ -0,0 +1,138 @@
+package pokemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DexConfig restricts which national dex numbers can be published. The zero
+// value allows every species PokeAPI knows about.
+type DexConfig struct {
+	// Max caps the highest dex number, e.g. to skip species whose data is
+	// still incomplete right after a new generation is released.
+	Max int
+
+	// RangeStart and RangeEnd restrict the dex numbers to an inclusive range.
+	RangeStart int
+	RangeEnd   int
+
+	// Generations restricts the species to those introduced in the listed
+	// generations.
+	Generations []int
+}
+
+// DexConfigFromEnv reads DEX_MAX, DEX_RANGE (e.g. "1-151") and
+// DEX_GENERATIONS (e.g. "1,2").
+func DexConfigFromEnv() (DexConfig, error) {
+	var cfg DexConfig
+
+	if dexMax := os.Getenv("DEX_MAX"); dexMax != "" {
+		parsed, err := strconv.Atoi(dexMax)
+		if err != nil || parsed < 1 {
+			return cfg, fmt.Errorf("DEX_MAX must be a positive number, got %q", dexMax)
+		}
+		cfg.Max = parsed
+	}
+
+	if dexRange := os.Getenv("DEX_RANGE"); dexRange != "" {
+		start, end, found := strings.Cut(dexRange, "-")
+		if !found {
+			return cfg, fmt.Errorf("DEX_RANGE must be formatted as start-end, got %q", dexRange)
+		}
+
+		var err error
+		if cfg.RangeStart, err = strconv.Atoi(strings.TrimSpace(start)); err != nil {
+			return cfg, fmt.Errorf("DEX_RANGE has an invalid start: %w", err)
+		}
+		if cfg.RangeEnd, err = strconv.Atoi(strings.TrimSpace(end)); err != nil {
+			return cfg, fmt.Errorf("DEX_RANGE has an invalid end: %w", err)
+		}
+	}
+
+	if generations := os.Getenv("DEX_GENERATIONS"); generations != "" {
+		for _, generation := range strings.Split(generations, ",") {
+			parsed, err := strconv.Atoi(strings.TrimSpace(generation))
+			if err != nil {
+				return cfg, fmt.Errorf("DEX_GENERATIONS must be a comma-separated list of numbers: %w", err)
+			}
+			cfg.Generations = append(cfg.Generations, parsed)
+		}
+	}
+
+	return cfg, nil
+}
+
+func (cfg DexConfig) String() string {
+	parts := []string{}
+	if cfg.Max != 0 {
+		parts = append(parts, fmt.Sprintf("DEX_MAX=%d", cfg.Max))
+	}
+	if cfg.RangeStart != 0 || cfg.RangeEnd != 0 {
+		parts = append(parts, fmt.Sprintf("DEX_RANGE=%d-%d", cfg.RangeStart, cfg.RangeEnd))
+	}
+	if len(cfg.Generations) > 0 {
+		generations := []string{}
+		for _, generation := range cfg.Generations {
+			generations = append(generations, strconv.Itoa(generation))
+		}
+		parts = append(parts, fmt.Sprintf("DEX_GENERATIONS=%s", strings.Join(generations, ",")))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// eligibleSpecies returns the dex numbers allowed by cfg, in ascending order.
+// The number of species is discovered from PokeAPI so new generations are
+// picked up without a code change.
+func eligibleSpecies(ctx context.Context, api PokeAPI, cfg DexConfig) ([]int, error) {
+	count, err := api.GetSpeciesCount(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover the number of species: %w", err)
+	}
+
+	start, end := 1, count
+	if cfg.Max != 0 && cfg.Max < end {
+		end = cfg.Max
+	}
+	if cfg.RangeStart > start {
+		start = cfg.RangeStart
+	}
+	if cfg.RangeEnd != 0 && cfg.RangeEnd < end {
+		end = cfg.RangeEnd
+	}
+
+	var allowed map[int]bool
+	if len(cfg.Generations) > 0 {
+		allowed = make(map[int]bool)
+		for _, generationID := range cfg.Generations {
+			generation, err := api.GetGeneration(ctx, generationID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get species of generation %d: %w", generationID, err)
+			}
+
+			for _, species := range generation.PokemonSpecies {
+				id, err := idFromURL(species.URL)
+				if err != nil {
+					return nil, err
+				}
+				allowed[id] = true
+			}
+		}
+	}
+
+	ids := []int{}
+	for id := start; id <= end; id++ {
+		if allowed == nil || allowed[id] {
+			ids = append(ids, id)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no species are eligible for publishing out of the %d known species with %s", count, cfg)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,111 @@
+package pokemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/bluesky"
+)
+
+// Draft is a post rendered by a dry run: the records that would be sent to
+// Bluesky and the images that would be uploaded. Blob and reply references
+// are placeholders since nothing is uploaded.
+type Draft struct {
+	Date      string                  `json:"date"`
+	PokemonID int                     `json:"pokemon_id"`
+	Shiny     bool                    `json:"shiny"`
+	Theme     string                  `json:"theme,omitempty"`
+	Records   []bluesky.ReqCreatePost `json:"records"`
+	Images    []DraftImage            `json:"images"`
+}
+
+// DryRun renders what Publish would post without uploading images, posting
+// to Bluesky or writing the history and selector state.
+func DryRun(opts PublishOptions) (Draft, error) {
+	ctx := context.Background()
+
+	bucket, err := newBucketFromEnv(ctx)
+	if err != nil {
+		return Draft{}, err
+	}
+
+	p, err := newPublisher(ctx, bucket, newBucketState(bucket))
+	if err != nil {
+		return Draft{}, err
+	}
+
+	planned, err := p.planFor(ctx, time.Now(), opts)
+	if err != nil {
+		return Draft{}, err
+	}
+
+	rendered, err := renderPost(ctx, p.api, planned.PokemonID, postOptions{Shiny: planned.Shiny, Theme: planned.Selection.Theme})
+	if err != nil {
+		return Draft{}, fmt.Errorf("failed to render pokemon #%d: %w", planned.PokemonID, err)
+	}
+	planned.Shiny = rendered.Shiny
+
+	result := Draft{
+		Date:      planned.Date,
+		PokemonID: planned.PokemonID,
+		Shiny:     planned.Shiny,
+		Images:    rendered.Images,
+	}
+	if planned.Selection.Theme != nil {
+		result.Theme = planned.Selection.Theme.Name
+	}
+
+	first := bluesky.PostParams{Text: rendered.Posts[0]}
+	for _, image := range rendered.Images {
+		first.Images = append(first.Images, bluesky.ImageDetails{
+			Alt: image.Alt,
+			Image: bluesky.RespImageUpload{
+				Type:     "blob",
+				Ref:      bluesky.Ref{Link: "dry-run:" + image.Name},
+				MimeType: image.MimeType,
+				Size:     len(image.Content),
+			},
+		})
+	}
+	result.Records = append(result.Records, bluesky.CreatePostBody(first))
+
+	root := bluesky.StrongRef{URI: "dry-run:post-1", Cid: "dry-run:post-1"}
+	for i, reply := range rendered.Posts[1:] {
+		parent := bluesky.StrongRef{URI: fmt.Sprintf("dry-run:post-%d", i+1), Cid: fmt.Sprintf("dry-run:post-%d", i+1)}
+		result.Records = append(result.Records, bluesky.CreatePostBody(bluesky.PostParams{
+			Text:  reply,
+			Reply: &bluesky.ReplyRef{Root: root, Parent: parent},
+		}))
+	}
+
+	return result, nil
+}
+
+// Write saves the records as post.json and each image under its own name in
+// dir.
+func (d Draft) Write(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create dry run directory %s: %w", dir, err)
+	}
+
+	for _, image := range d.Images {
+		if err := os.WriteFile(filepath.Join(dir, image.Name), image.Content, 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", image.Name, err)
+		}
+	}
+
+	content, err := json.MarshalIndent(d.Records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode post records: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "post.json"), content, 0o644); err != nil {
+		return fmt.Errorf("failed to write post records: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package pokemon
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// idFromURL returns the trailing numeric ID of a PokeAPI resource URL such as
+// https://pokeapi.co/api/v2/evolution-chain/1/.
+func idFromURL(url string) (int, error) {
+	segments := strings.Split(strings.TrimSuffix(url, "/"), "/")
+
+	id, err := strconv.Atoi(segments[len(segments)-1])
+	if err != nil {
+		return 0, fmt.Errorf("resource URL %q does not end in an ID: %w", url, err)
+	}
+
+	return id, nil
+}
+
+// formatName turns an API slug such as "water-stone" into "Water Stone".
+func formatName(slug string) string {
+	return cases.Title(language.Und).String(strings.ReplaceAll(slug, "-", " "))
+}
+
+// formatEvolutionChain renders every branch of the chain on its own line, e.g.
+// "Bulbasaur → Ivysaur (Lv 16) → Venusaur (Lv 32)". names maps species slugs
+// to display names; slugs missing from it are title-cased. It returns an
+// empty string for Pokemon that do not evolve.
+func formatEvolutionChain(chain RespEvolutionChain, names map[string]string) string {
+	if len(chain.Chain.EvolvesTo) == 0 {
+		return ""
+	}
+
+	lines := []string{}
+
+	var walk func(link ChainLink, prefix string)
+	walk = func(link ChainLink, prefix string) {
+		stage, ok := names[link.Species.Name]
+		if !ok {
+			stage = formatName(link.Species.Name)
+		}
+		if len(link.EvolutionDetails) > 0 {
+			stage = fmt.Sprintf("%s (%s)", stage, describeEvolution(link.EvolutionDetails[0]))
+		}
+
+		if prefix != "" {
+			stage = prefix + " → " + stage
+		}
+
+		if len(link.EvolvesTo) == 0 {
+			lines = append(lines, stage)
+			return
+		}
+
+		for _, next := range link.EvolvesTo {
+			walk(next, stage)
+		}
+	}
+	walk(chain.Chain, "")
+
+	return "Evolution:\n" + strings.Join(lines, "\n")
+}
+
+// describeEvolution summarizes what triggers an evolution, e.g. "Lv 16",
+// "Water Stone", "Trade holding Metal Coat" or "Friendship, Night".
+func describeEvolution(details EvolutionDetails) string {
+	switch details.Trigger.Name {
+	case "level-up":
+		conditions := []string{}
+		if details.MinHappiness > 0 {
+			conditions = append(conditions, "Friendship")
+		}
+		if details.MinAffection > 0 {
+			conditions = append(conditions, "Affection")
+		}
+		if details.KnownMoveType != nil {
+			conditions = append(conditions, fmt.Sprintf("knowing a %s move", formatName(details.KnownMoveType.Name)))
+		}
+		if details.HeldItem != nil {
+			conditions = append(conditions, fmt.Sprintf("holding %s", formatName(details.HeldItem.Name)))
+		}
+		if details.Location != nil {
+			conditions = append(conditions, fmt.Sprintf("at %s", formatName(details.Location.Name)))
+		}
+		if details.TimeOfDay != "" {
+			conditions = append(conditions, formatName(details.TimeOfDay))
+		}
+
+		if details.MinLevel > 0 {
+			conditions = append([]string{fmt.Sprintf("Lv %d", details.MinLevel)}, conditions...)
+		}
+
+		if len(conditions) == 0 {
+			return "Level up"
+		}
+
+		return strings.Join(conditions, ", ")
+	case "use-item":
+		if details.Item != nil {
+			return formatName(details.Item.Name)
+		}
+	case "trade":
+		if details.HeldItem != nil {
+			return fmt.Sprintf("Trade holding %s", formatName(details.HeldItem.Name))
+		}
+		if details.TradeSpecies != nil {
+			return fmt.Sprintf("Trade for %s", formatName(details.TradeSpecies.Name))
+		}
+		return "Trade"
+	}
+
+	return formatName(details.Trigger.Name)
+}
+
+// speciesNames looks up the display name of every species in the chain.
+// Species that cannot be looked up are left out.
+func speciesNames(ctx context.Context, api PokeAPI, chain RespEvolutionChain) map[string]string {
+	names := make(map[string]string)
+
+	var walk func(link ChainLink)
+	walk = func(link ChainLink) {
+		if id, err := idFromURL(link.Species.URL); err == nil {
+			if species, err := api.GetSpecies(ctx, id); err == nil {
+				if name := englishName(species.Names); name != "" {
+					names[link.Species.Name] = name
+				}
+			}
+		}
+
+		for _, next := range link.EvolvesTo {
+			walk(next)
+		}
+	}
+	walk(chain.Chain)
+
+	return names
+}
@@ -0,0 +1,123 @@
+package pokemon
+
+import "testing"
+
+func TestDescribeEvolution(t *testing.T) {
+	tests := []struct {
+		name    string
+		details EvolutionDetails
+		want    string
+	}{
+		{
+			name:    "level",
+			details: EvolutionDetails{Trigger: EvolutionTrigger{Name: "level-up"}, MinLevel: 16},
+			want:    "Lv 16",
+		},
+		{
+			name:    "friendship at night",
+			details: EvolutionDetails{Trigger: EvolutionTrigger{Name: "level-up"}, MinHappiness: 160, TimeOfDay: "night"},
+			want:    "Friendship, Night",
+		},
+		{
+			name: "level holding an item during the day",
+			details: EvolutionDetails{
+				Trigger:   EvolutionTrigger{Name: "level-up"},
+				MinLevel:  25,
+				HeldItem:  &Item{Name: "oval-stone"},
+				TimeOfDay: "day",
+			},
+			want: "Lv 25, holding Oval Stone, Day",
+		},
+		{
+			name:    "level up without conditions",
+			details: EvolutionDetails{Trigger: EvolutionTrigger{Name: "level-up"}},
+			want:    "Level up",
+		},
+		{
+			name:    "item",
+			details: EvolutionDetails{Trigger: EvolutionTrigger{Name: "use-item"}, Item: &Item{Name: "water-stone"}},
+			want:    "Water Stone",
+		},
+		{
+			name:    "trade holding an item",
+			details: EvolutionDetails{Trigger: EvolutionTrigger{Name: "trade"}, HeldItem: &Item{Name: "metal-coat"}},
+			want:    "Trade holding Metal Coat",
+		},
+		{
+			name:    "trade for a species",
+			details: EvolutionDetails{Trigger: EvolutionTrigger{Name: "trade"}, TradeSpecies: &Species{Name: "shelmet"}},
+			want:    "Trade for Shelmet",
+		},
+		{
+			name:    "other trigger",
+			details: EvolutionDetails{Trigger: EvolutionTrigger{Name: "three-critical-hits"}},
+			want:    "Three Critical Hits",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := describeEvolution(tt.details); got != tt.want {
+				t.Errorf("describeEvolution = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatEvolutionChain(t *testing.T) {
+	level := func(species string, lv int) ChainLink {
+		return ChainLink{
+			Species:          Species{Name: species},
+			EvolutionDetails: []EvolutionDetails{{Trigger: EvolutionTrigger{Name: "level-up"}, MinLevel: lv}},
+		}
+	}
+	stone := func(species, item string) ChainLink {
+		return ChainLink{
+			Species:          Species{Name: species},
+			EvolutionDetails: []EvolutionDetails{{Trigger: EvolutionTrigger{Name: "use-item"}, Item: &Item{Name: item}}},
+		}
+	}
+
+	ivysaur := level("ivysaur", 16)
+	ivysaur.EvolvesTo = []ChainLink{level("venusaur", 32)}
+
+	tests := []struct {
+		name  string
+		chain ChainLink
+		names map[string]string
+		want  string
+	}{
+		{
+			name:  "does not evolve",
+			chain: ChainLink{Species: Species{Name: "tauros"}},
+		},
+		{
+			name:  "linear",
+			chain: ChainLink{Species: Species{Name: "bulbasaur"}, EvolvesTo: []ChainLink{ivysaur}},
+			want:  "Evolution:\nBulbasaur → Ivysaur (Lv 16) → Venusaur (Lv 32)",
+		},
+		{
+			name: "branching",
+			chain: ChainLink{Species: Species{Name: "eevee"}, EvolvesTo: []ChainLink{
+				stone("vaporeon", "water-stone"),
+				stone("jolteon", "thunder-stone"),
+			}},
+			want: "Evolution:\nEevee → Vaporeon (Water Stone)\nEevee → Jolteon (Thunder Stone)",
+		},
+		{
+			name:  "display names",
+			chain: ChainLink{Species: Species{Name: "mr-mime"}, EvolvesTo: []ChainLink{level("mr-rime", 42)}},
+			names: map[string]string{"mr-mime": "Mr. Mime", "mr-rime": "Mr. Rime"},
+			want:  "Evolution:\nMr. Mime → Mr. Rime (Lv 42)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatEvolutionChain(RespEvolutionChain{Chain: tt.chain}, tt.names)
+			if got != tt.want {
+				t.Errorf("formatEvolutionChain = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
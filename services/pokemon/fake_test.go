@@ -0,0 +1,111 @@
+package pokemon
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// fakeAPI is an in-memory PokeAPI that counts the lookups made through it.
+type fakeAPI struct {
+	pokemon     map[int]RespPokemon
+	species     map[int]RespPokemonSpecies
+	chains      map[int]RespEvolutionChain
+	abilities   map[string]RespAbility
+	forms       map[string]RespPokemonForm
+	types       map[string]RespType
+	generations map[int]RespGeneration
+	sprites     map[string][]byte
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newFakeAPI() *fakeAPI {
+	return &fakeAPI{
+		pokemon:     make(map[int]RespPokemon),
+		species:     make(map[int]RespPokemonSpecies),
+		chains:      make(map[int]RespEvolutionChain),
+		abilities:   make(map[string]RespAbility),
+		forms:       make(map[string]RespPokemonForm),
+		types:       make(map[string]RespType),
+		generations: make(map[int]RespGeneration),
+		sprites:     make(map[string][]byte),
+		calls:       make(map[string]int),
+	}
+}
+
+func (f *fakeAPI) count(method string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls[method]++
+}
+
+func (f *fakeAPI) callsTo(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+func lookup[K comparable, V any](m map[K]V, kind string, key K) (V, error) {
+	v, ok := m[key]
+	if !ok {
+		return v, fmt.Errorf("%s %v not found", kind, key)
+	}
+	return v, nil
+}
+
+func (f *fakeAPI) GetPokemon(ctx context.Context, id int) (RespPokemon, error) {
+	f.count("GetPokemon")
+	return lookup(f.pokemon, "pokemon", id)
+}
+
+func (f *fakeAPI) GetPokemonByName(ctx context.Context, name string) (RespPokemon, error) {
+	f.count("GetPokemonByName")
+	for _, pokemon := range f.pokemon {
+		if pokemon.Name == name {
+			return pokemon, nil
+		}
+	}
+	return RespPokemon{}, fmt.Errorf("pokemon %s not found", name)
+}
+
+func (f *fakeAPI) GetSpecies(ctx context.Context, id int) (RespPokemonSpecies, error) {
+	f.count("GetSpecies")
+	return lookup(f.species, "species", id)
+}
+
+func (f *fakeAPI) GetEvolutionChain(ctx context.Context, id int) (RespEvolutionChain, error) {
+	f.count("GetEvolutionChain")
+	return lookup(f.chains, "evolution chain", id)
+}
+
+func (f *fakeAPI) GetAbility(ctx context.Context, name string) (RespAbility, error) {
+	f.count("GetAbility")
+	return lookup(f.abilities, "ability", name)
+}
+
+func (f *fakeAPI) GetPokemonForm(ctx context.Context, name string) (RespPokemonForm, error) {
+	f.count("GetPokemonForm")
+	return lookup(f.forms, "pokemon form", name)
+}
+
+func (f *fakeAPI) GetType(ctx context.Context, name string) (RespType, error) {
+	f.count("GetType")
+	return lookup(f.types, "type", name)
+}
+
+func (f *fakeAPI) GetSpeciesCount(ctx context.Context) (int, error) {
+	f.count("GetSpeciesCount")
+	return len(f.species), nil
+}
+
+func (f *fakeAPI) GetGeneration(ctx context.Context, id int) (RespGeneration, error) {
+	f.count("GetGeneration")
+	return lookup(f.generations, "generation", id)
+}
+
+func (f *fakeAPI) GetSprite(ctx context.Context, url string) ([]byte, error) {
+	f.count("GetSprite")
+	return lookup(f.sprites, "sprite", url)
+}
@@ -0,0 +1,249 @@
+package pokemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// filtersObject is the bucket object holding the allowlist and blocklist, so
+// they can be changed without redeploying.
+const filtersObject = "filters.json"
+
+// filtersFile is the format of the filters object. When the allowlist sets
+// anything, only pokemon matching at least one of its entries are published.
+// Pokemon matching any entry of the blocklist are never published by the
+// daily selection.
+type filtersFile struct {
+	Allow pokemonFilter `json:"allow"`
+	Block pokemonFilter `json:"block"`
+}
+
+// pokemonFilter matches pokemon by any of its entries.
+type pokemonFilter struct {
+	// IDs are PokeAPI pokemon IDs and Names their names, so forms such as
+	// "pikachu-gmax" can be listed on their own.
+	IDs         []int    `json:"ids"`
+	Names       []string `json:"names"`
+	Types       []string `json:"types"`
+	Generations []int    `json:"generations"`
+	Legendary   bool     `json:"legendary"`
+	Mythical    bool     `json:"mythical"`
+}
+
+func (f pokemonFilter) empty() bool {
+	return len(f.IDs) == 0 && len(f.Names) == 0 && len(f.Types) == 0 && len(f.Generations) == 0 && !f.Legendary && !f.Mythical
+}
+
+// resolvedFilter is a pokemonFilter with names, types and generations looked
+// up to pokemon IDs.
+type resolvedFilter struct {
+	ids       map[int]bool
+	legendary bool
+	mythical  bool
+}
+
+func (f pokemonFilter) resolve(ctx context.Context, api PokeAPI) (resolvedFilter, error) {
+	resolved := resolvedFilter{
+		ids:       make(map[int]bool),
+		legendary: f.Legendary,
+		mythical:  f.Mythical,
+	}
+
+	for _, id := range f.IDs {
+		resolved.ids[id] = true
+	}
+
+	for _, name := range f.Names {
+		pokemon, err := api.GetPokemonByName(ctx, strings.ToLower(name))
+		if err != nil {
+			return resolved, fmt.Errorf("failed to look up pokemon %s: %w", name, err)
+		}
+		resolved.ids[pokemon.ID] = true
+	}
+
+	for _, name := range f.Types {
+		pokemonType, err := api.GetType(ctx, strings.ToLower(name))
+		if err != nil {
+			return resolved, fmt.Errorf("failed to get pokemon of type %s: %w", name, err)
+		}
+
+		for _, pokemon := range pokemonType.Pokemon {
+			id, err := idFromURL(pokemon.Pokemon.URL)
+			if err != nil {
+				return resolved, err
+			}
+			resolved.ids[id] = true
+		}
+	}
+
+	for _, generationID := range f.Generations {
+		generation, err := api.GetGeneration(ctx, generationID)
+		if err != nil {
+			return resolved, fmt.Errorf("failed to get species of generation %d: %w", generationID, err)
+		}
+
+		for _, species := range generation.PokemonSpecies {
+			id, err := idFromURL(species.URL)
+			if err != nil {
+				return resolved, err
+			}
+			resolved.ids[id] = true
+		}
+	}
+
+	return resolved, nil
+}
+
+// matchesSpecies reports whether the species with national dex number id
+// matches the filter. The species is only fetched when the filter needs its
+// legendary or mythical flag.
+func (f resolvedFilter) matchesSpecies(ctx context.Context, api PokeAPI, id int) (bool, error) {
+	if f.ids[id] || !f.needsSpecies() {
+		return f.ids[id], nil
+	}
+
+	flag, err := fetchSpeciesFlag(ctx, api, id)
+	if err != nil {
+		return false, err
+	}
+
+	return f.matches(id, flag), nil
+}
+
+// matches reports whether the species with national dex number id and the
+// given flags matches the filter.
+func (f resolvedFilter) matches(id int, flag speciesFlag) bool {
+	return f.ids[id] || (f.legendary && flag.Legendary) || (f.mythical && flag.Mythical)
+}
+
+// needsSpecies reports whether matching the filter needs the species fetched.
+func (f resolvedFilter) needsSpecies() bool {
+	return f.legendary || f.mythical
+}
+
+// speciesFlagsObject caches the legendary and mythical flags of every species
+// checked so far. The flags of a species never change, so each species is
+// fetched once rather than on every run.
+const speciesFlagsObject = "species-flags.json"
+
+// speciesFlag is the cached legendary and mythical flags of a species.
+type speciesFlag struct {
+	Legendary bool `json:"legendary,omitempty"`
+	Mythical  bool `json:"mythical,omitempty"`
+}
+
+func fetchSpeciesFlag(ctx context.Context, api PokeAPI, id int) (speciesFlag, error) {
+	species, err := api.GetSpecies(ctx, id)
+	if err != nil {
+		return speciesFlag{}, fmt.Errorf("failed to get species %d: %w", id, err)
+	}
+
+	return speciesFlag{Legendary: species.IsLegendary, Mythical: species.IsMythical}, nil
+}
+
+// loadSpeciesFlags returns the flags of the species ids, fetching those that
+// are not cached yet. Failing to save the new flags only means they are
+// fetched again on the next run.
+func loadSpeciesFlags(ctx context.Context, api PokeAPI, state selectionState, ids []int) (map[int]speciesFlag, error) {
+	flags := make(map[int]speciesFlag)
+	if _, err := state.readState(ctx, speciesFlagsObject, &flags); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", speciesFlagsObject, err)
+	}
+
+	fetched := 0
+	for _, id := range ids {
+		if _, ok := flags[id]; ok {
+			continue
+		}
+
+		flag, err := fetchSpeciesFlag(ctx, api, id)
+		if err != nil {
+			return nil, err
+		}
+		flags[id] = flag
+		fetched++
+	}
+
+	if fetched > 0 {
+		if err := state.writeState(ctx, speciesFlagsObject, flags); err != nil {
+			logger := zerolog.New(os.Stdout)
+			logger.Err(err).Msgf("failed to save %s; the flags of %d species will be fetched again", speciesFlagsObject, fetched)
+		}
+	}
+
+	return flags, nil
+}
+
+// listFilters is the allowlist and blocklist in effect for a run.
+type listFilters struct {
+	allow    resolvedFilter
+	hasAllow bool
+	block    resolvedFilter
+}
+
+// loadListFilters reads the filters object from state. There are no filters
+// if it does not exist.
+func loadListFilters(ctx context.Context, api PokeAPI, state selectionState) (listFilters, error) {
+	var file filtersFile
+	if _, err := state.readState(ctx, filtersObject, &file); err != nil {
+		return listFilters{}, fmt.Errorf("failed to read %s: %w", filtersObject, err)
+	}
+
+	allow, err := file.Allow.resolve(ctx, api)
+	if err != nil {
+		return listFilters{}, fmt.Errorf("failed to resolve allowlist: %w", err)
+	}
+
+	block, err := file.Block.resolve(ctx, api)
+	if err != nil {
+		return listFilters{}, fmt.Errorf("failed to resolve blocklist: %w", err)
+	}
+
+	return listFilters{allow: allow, hasAllow: !file.Allow.empty(), block: block}, nil
+}
+
+// apply returns the species of eligible allowed by the filters. When they
+// filter by the legendary or mythical flag, the flags are read from
+// speciesFlagsObject, so only species missing from it are fetched.
+func (f listFilters) apply(ctx context.Context, api PokeAPI, state selectionState, eligible []int) ([]int, error) {
+	var flags map[int]speciesFlag
+	if f.allow.needsSpecies() || f.block.needsSpecies() {
+		var err error
+		if flags, err = loadSpeciesFlags(ctx, api, state, eligible); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := []int{}
+	for _, id := range eligible {
+		if f.hasAllow && !f.allow.matches(id, flags[id]) {
+			continue
+		}
+		if f.block.matches(id, flags[id]) {
+			continue
+		}
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("the allowlist and blocklist in %s leave no eligible species", filtersObject)
+	}
+
+	return ids, nil
+}
+
+// blocksSpecies reports whether the species with national dex number id is
+// on the blocklist.
+func (f listFilters) blocksSpecies(ctx context.Context, api PokeAPI, id int) (bool, error) {
+	return f.block.matchesSpecies(ctx, api, id)
+}
+
+// blocks reports whether a variety is blocked by its pokemon ID, e.g. a form
+// listed by name.
+func (f listFilters) blocks(pokemonID int) bool {
+	return f.block.ids[pokemonID]
+}
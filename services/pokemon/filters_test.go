@@ -0,0 +1,94 @@
+package pokemon
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud/memory"
+)
+
+func TestListFiltersApply(t *testing.T) {
+	// 144 is legendary and 151 mythical
+	eligible := []int{1, 4, 144, 151, 152}
+
+	tests := []struct {
+		name    string
+		filters listFilters
+		want    []int
+	}{
+		{
+			name: "no filters",
+			want: eligible,
+		},
+		{
+			name:    "block by ID",
+			filters: listFilters{block: resolvedFilter{ids: map[int]bool{4: true}}},
+			want:    []int{1, 144, 151, 152},
+		},
+		{
+			name:    "block legendary and mythical",
+			filters: listFilters{block: resolvedFilter{legendary: true, mythical: true}},
+			want:    []int{1, 4, 152},
+		},
+		{
+			name:    "allow legendary or by ID",
+			filters: listFilters{allow: resolvedFilter{ids: map[int]bool{1: true}, legendary: true}, hasAllow: true},
+			want:    []int{1, 144},
+		},
+		{
+			name: "allow mythical, block by ID",
+			filters: listFilters{
+				allow:    resolvedFilter{mythical: true},
+				hasAllow: true,
+				block:    resolvedFilter{ids: map[int]bool{151: true}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newFakeAPI()
+			for _, id := range eligible {
+				api.species[id] = RespPokemonSpecies{IsLegendary: id == 144, IsMythical: id == 151}
+			}
+			state := newBucketState(memory.NewBucket())
+
+			got, err := tt.filters.apply(context.Background(), api, state, eligible)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("apply = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("apply failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("apply = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestListFiltersCacheSpeciesFlags checks that the legendary and mythical
+// flags are fetched once per species across runs.
+func TestListFiltersCacheSpeciesFlags(t *testing.T) {
+	ctx := context.Background()
+	api := newFakeAPI()
+	for id := 1; id <= 10; id++ {
+		api.species[id] = RespPokemonSpecies{IsLegendary: id == 3}
+	}
+	bucket := memory.NewBucket()
+	filters := listFilters{block: resolvedFilter{legendary: true}}
+
+	for run, eligible := range [][]int{{1, 2, 3, 4, 5}, {1, 2, 3, 4, 5}, {1, 2, 3, 4, 5, 6, 7, 8, 9, 10}} {
+		if _, err := filters.apply(ctx, api, newBucketState(bucket), eligible); err != nil {
+			t.Fatalf("run %d: apply failed: %v", run, err)
+		}
+	}
+
+	if calls := api.callsTo("GetSpecies"); calls != 10 {
+		t.Errorf("GetSpecies was called %d times, want 10", calls)
+	}
+}
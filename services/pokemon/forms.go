@@ -0,0 +1,174 @@
+package pokemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/rand"
+)
+
+// regions are the regions whose variants of a species are featured.
+var regions = []string{"alola", "galar", "hisui", "paldea"}
+
+// includeFormsFromEnv reads INCLUDE_FORMS, which defaults to true.
+func includeFormsFromEnv() (bool, error) {
+	includeForms := os.Getenv("INCLUDE_FORMS")
+	if includeForms == "" {
+		return true, nil
+	}
+
+	parsed, err := strconv.ParseBool(includeForms)
+	if err != nil {
+		return false, fmt.Errorf("INCLUDE_FORMS must be a boolean: %w", err)
+	}
+
+	return parsed, nil
+}
+
+// isSelectableForm reports whether the non-default variety name of the
+// species speciesName is one of the forms the bot features: regional
+// variants, Mega Evolutions and Gigantamax forms. The rest of the name after
+// the species is matched exactly, so cosmetic and battle-only forms of a
+// regional variant such as "pikachu-alola-cap" or "darmanitan-galar-zen" are
+// left out, while regional variants that come in breeds or modes such as
+// "tauros-paldea-aqua-breed" or "darmanitan-galar-standard" are kept.
+func isSelectableForm(speciesName string, name string) bool {
+	// Gigantamax forms may be of another form, e.g.
+	// "urshifu-rapid-strike-gmax".
+	if strings.HasSuffix(name, "-gmax") {
+		return true
+	}
+
+	form, ok := strings.CutPrefix(name, speciesName+"-")
+	if !ok {
+		return false
+	}
+
+	switch form {
+	case "mega", "mega-x", "mega-y":
+		return true
+	}
+
+	for _, region := range regions {
+		rest, ok := strings.CutPrefix(form, region)
+		if !ok {
+			continue
+		}
+		if rest == "" || rest == "-standard" || (strings.HasPrefix(rest, "-") && strings.HasSuffix(rest, "-breed")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// selectableVarieties returns the pokemon IDs that can be published for a
+// species: the default variety and, when includeForms is set, its
+// selectable forms. Each variety has its own pokemon ID, and the default
+// variety's ID is the species' national dex number, so these IDs double as
+// history keys.
+func selectableVarieties(species RespPokemonSpecies, speciesID int, includeForms bool) []int {
+	ids := []int{}
+
+	for _, variety := range species.Varieties {
+		if !variety.IsDefault && (!includeForms || !isSelectableForm(species.Name, variety.Pokemon.Name)) {
+			continue
+		}
+
+		id, err := idFromURL(variety.Pokemon.URL)
+		if err != nil {
+			continue
+		}
+
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		ids = append(ids, speciesID)
+	}
+
+	return ids
+}
+
+// chooseVariety picks which variety of a species to publish, preferring
+// varieties that have never been published. Varieties on the blocklist are
+// left out. If the species cannot be fetched only the default variety is
+// considered.
+func chooseVariety(ctx context.Context, api PokeAPI, state selectionState, filters listFilters, speciesID int, includeForms bool, rng *rand.Rand) (int, error) {
+	ids := []int{speciesID}
+
+	species, err := api.GetSpecies(ctx, speciesID)
+	if err == nil {
+		ids = []int{}
+		for _, id := range selectableVarieties(species, speciesID, includeForms) {
+			if !filters.blocks(id) {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) == 0 {
+			ids = append(ids, speciesID)
+		}
+	}
+
+	unpublished := []int{}
+	var errs []string
+
+	for _, id := range ids {
+		published, readErr := state.isPublished(ctx, id)
+		if readErr != nil {
+			errs = append(errs, fmt.Sprintf("#%d: %s", id, readErr.Error()))
+		}
+		if !published {
+			unpublished = append(unpublished, id)
+		}
+	}
+
+	// every variety has been published in an earlier season
+	if len(unpublished) == 0 {
+		unpublished = ids
+	}
+
+	chosen := unpublished[rng.Intn(len(unpublished))]
+
+	if err != nil {
+		return chosen, fmt.Errorf("failed to get varieties of species %d: %w", speciesID, err)
+	}
+
+	if len(errs) > 0 {
+		return chosen, fmt.Errorf("failed to check history of %s", strings.Join(errs, ", "))
+	}
+
+	return chosen, nil
+}
+
+// getForm fetches the form of a pokemon, which holds the localized name of
+// non-default varieties like "Alolan Raichu" and the form name itself.
+func getForm(ctx context.Context, api PokeAPI, pokemon RespPokemon) (RespPokemonForm, error) {
+	if len(pokemon.Forms) == 0 {
+		return RespPokemonForm{}, nil
+	}
+
+	form, err := api.GetPokemonForm(ctx, pokemon.Forms[0].Name)
+	if err != nil {
+		return form, fmt.Errorf("failed to get form %s: %w", pokemon.Forms[0].Name, err)
+	}
+
+	return form, nil
+}
+
+// formLabel returns the English name of a non-default form, e.g.
+// "Alola Form" or "Gigantamax", or an empty string for default forms.
+func formLabel(form RespPokemonForm) string {
+	if form.IsDefault || form.FormName == "" {
+		return ""
+	}
+
+	if label := englishName(form.FormNames); label != "" {
+		return label
+	}
+
+	return formatName(form.FormName)
+}
@@ -0,0 +1,56 @@
+package pokemon
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsSelectableForm(t *testing.T) {
+	tests := []struct {
+		species string
+		name    string
+		want    bool
+	}{
+		{species: "raichu", name: "raichu-alola", want: true},
+		{species: "mr-mime", name: "mr-mime-galar", want: true},
+		{species: "growlithe", name: "growlithe-hisui", want: true},
+		{species: "wooper", name: "wooper-paldea", want: true},
+		{species: "tauros", name: "tauros-paldea-aqua-breed", want: true},
+		{species: "darmanitan", name: "darmanitan-galar-standard", want: true},
+		{species: "venusaur", name: "venusaur-mega", want: true},
+		{species: "charizard", name: "charizard-mega-x", want: true},
+		{species: "charizard", name: "charizard-gmax", want: true},
+		{species: "urshifu", name: "urshifu-rapid-strike-gmax", want: true},
+		{species: "pikachu", name: "pikachu-alola-cap", want: false},
+		{species: "darmanitan", name: "darmanitan-galar-zen", want: false},
+		{species: "darmanitan", name: "darmanitan-zen", want: false},
+		{species: "pikachu", name: "pikachu-rock-star", want: false},
+		{species: "pikachu", name: "pikachu-partner-cap", want: false},
+		{species: "castform", name: "castform-sunny", want: false},
+		{species: "greninja", name: "greninja-battle-bond", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := isSelectableForm(tt.species, tt.name); got != tt.want {
+			t.Errorf("isSelectableForm(%q, %q) = %v, want %v", tt.species, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSelectableVarieties(t *testing.T) {
+	species := RespPokemonSpecies{
+		Name: "pikachu",
+		Varieties: []Varieties{
+			{IsDefault: true, Pokemon: Pokemon{Name: "pikachu", URL: "https://pokeapi.co/api/v2/pokemon/25/"}},
+			{Pokemon: Pokemon{Name: "pikachu-alola-cap", URL: "https://pokeapi.co/api/v2/pokemon/10099/"}},
+			{Pokemon: Pokemon{Name: "pikachu-gmax", URL: "https://pokeapi.co/api/v2/pokemon/10199/"}},
+		},
+	}
+
+	if got := selectableVarieties(species, 25, true); !reflect.DeepEqual(got, []int{25, 10199}) {
+		t.Errorf("selectableVarieties with forms = %v, want [25 10199]", got)
+	}
+	if got := selectableVarieties(species, 25, false); !reflect.DeepEqual(got, []int{25}) {
+		t.Errorf("selectableVarieties without forms = %v, want [25]", got)
+	}
+}
@@ -0,0 +1,248 @@
+package pokemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+)
+
+// BuildVersion identifies the build of the bot in the history. It can be set
+// with -ldflags "-X .../services/pokemon.BuildVersion=v1.2.3"; otherwise
+// BOT_VERSION or the VCS revision of the build is used.
+var BuildVersion = ""
+
+func botVersion() string {
+	if BuildVersion != "" {
+		return BuildVersion
+	}
+	if version := os.Getenv("BOT_VERSION"); version != "" {
+		return version
+	}
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				return setting.Value
+			}
+		}
+	}
+
+	return "unknown"
+}
+
+// historyEntry is the content of the history objects written for each
+// publish. The latest is named after the pokemon ID, so whether a pokemon has
+// been published is still answered by the object existing. Objects written
+// by older versions are empty or only have the shiny flag.
+type historyEntry struct {
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	PokemonID   int        `json:"pokemon_id,omitempty"`
+	SpeciesID   int        `json:"species_id,omitempty"`
+	Name        string     `json:"name,omitempty"`
+	Form        string     `json:"form,omitempty"`
+	Shiny       bool       `json:"shiny"`
+
+	PostURI   string   `json:"post_uri,omitempty"`
+	PostCID   string   `json:"post_cid,omitempty"`
+	ImageCIDs []string `json:"image_cids,omitempty"`
+
+	Season     int    `json:"season,omitempty"`
+	Theme      string `json:"theme,omitempty"`
+	BotVersion string `json:"bot_version,omitempty"`
+}
+
+func newHistoryEntry(now time.Time, planned plan, rendered draft, sent sentPost) historyEntry {
+	publishedAt := now.UTC()
+	entry := historyEntry{
+		PublishedAt: &publishedAt,
+		PokemonID:   planned.PokemonID,
+		SpeciesID:   planned.Selection.SpeciesID,
+		Name:        rendered.Name,
+		Form:        rendered.Form,
+		Shiny:       planned.Shiny,
+		PostURI:     sent.Post.URI,
+		PostCID:     sent.Post.Cid,
+		Season:      planned.Selection.Season,
+		BotVersion:  botVersion(),
+	}
+
+	for _, image := range sent.Images {
+		entry.ImageCIDs = append(entry.ImageCIDs, image.Ref.Link)
+	}
+
+	if planned.Selection.Theme != nil {
+		entry.Theme = planned.Selection.Theme.Name
+	}
+
+	return entry
+}
+
+func historyObject(pokemonID int) string {
+	return fmt.Sprintf("%d", pokemonID)
+}
+
+// historyRecordPrefix holds one object per publish, under a directory per
+// pokemon, so publishing a pokemon again keeps the records of its earlier
+// publishes. The object named after the pokemon ID holds the latest record.
+const historyRecordPrefix = "history/"
+
+// historyRecordTime names records in their publish order. It leaves out the
+// colons of RFC 3339, which are not allowed in file names on Windows.
+const historyRecordTime = "20060102T150405Z"
+
+func historyRecordsPrefix(pokemonID int) string {
+	return fmt.Sprintf("%s%d/", historyRecordPrefix, pokemonID)
+}
+
+// historyRecordObject returns the record object of entry. Entries of older
+// versions have no publish time and therefore no record object.
+func historyRecordObject(entry historyEntry) (string, bool) {
+	if entry.PublishedAt == nil {
+		return "", false
+	}
+
+	return historyRecordsPrefix(entry.PokemonID) + entry.PublishedAt.UTC().Format(historyRecordTime) + ".json", true
+}
+
+// updateHistory writes the record of a publish and makes it the latest
+// history of the pokemon.
+func updateHistory(ctx context.Context, bucket cloud.FileBucket, entry historyEntry) error {
+	if record, ok := historyRecordObject(entry); ok {
+		if err := writeHistoryObject(ctx, bucket, record, entry); err != nil {
+			return err
+		}
+	}
+
+	return writeHistoryObject(ctx, bucket, historyObject(entry.PokemonID), entry)
+}
+
+func writeHistoryObject(ctx context.Context, bucket cloud.FileBucket, object string, entry historyEntry) error {
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode history of pokemon #%d: %w", entry.PokemonID, err)
+	}
+
+	return cloud.CreateFileWithAttrs(ctx, bucket, object, content, historyAttrs(entry))
+}
+
+// historyAttrs label the history object with the pokemon and its post, where
+// the backend supports it, so they show up in the bucket listing.
+func historyAttrs(entry historyEntry) cloud.ObjectAttrs {
+	attrs := cloud.ObjectAttrs{ContentType: "application/json", Metadata: map[string]string{}}
+
+	if entry.Name != "" {
+		attrs.Metadata["pokemon-name"] = entry.Name
+	}
+	if entry.PostURI != "" {
+		attrs.Metadata["post-uri"] = entry.PostURI
+	}
+
+	return attrs
+}
+
+// listHistory returns the IDs of every pokemon in the history. History
+// objects are the top-level objects named after a pokemon ID; other objects
+// such as deck.json are skipped.
+func listHistory(ctx context.Context, bucket cloud.FileBucket) (map[int]bool, error) {
+	names, err := bucket.List(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the history: %w", err)
+	}
+
+	published := make(map[int]bool, len(names))
+	for _, name := range names {
+		if id, err := strconv.Atoi(name); err == nil {
+			published[id] = true
+		}
+	}
+
+	return published, nil
+}
+
+// firstFormID is the lowest pokemon ID PokeAPI gives to a variety that is not
+// the default of its species. Below it, a pokemon ID is also the national dex
+// number of its species.
+const firstFormID = 10001
+
+// listPublishedSpecies returns the species with any variety in published, the
+// pokemon IDs in the history. The species of a form is read from its history
+// entry, so this costs one read per published form rather than per pokemon.
+func listPublishedSpecies(ctx context.Context, bucket cloud.FileBucket, published map[int]bool) (map[int]bool, error) {
+	species := make(map[int]bool, len(published))
+	for id := range published {
+		if id < firstFormID {
+			species[id] = true
+			continue
+		}
+
+		entry, err := readHistoryEntry(ctx, bucket, id)
+		if err != nil {
+			return nil, err
+		}
+		if entry.SpeciesID != 0 {
+			species[entry.SpeciesID] = true
+		}
+	}
+
+	return species, nil
+}
+
+// readHistoryEntry reads back the history of a published pokemon. Empty
+// objects written by older versions yield an entry with only the pokemon ID
+// set.
+func readHistoryEntry(ctx context.Context, bucket cloud.FileBucket, pokemonID int) (historyEntry, error) {
+	content, err := bucket.ReadFile(ctx, historyObject(pokemonID))
+	if err != nil {
+		return historyEntry{}, fmt.Errorf("failed to read history of pokemon #%d: %w", pokemonID, err)
+	}
+
+	return decodeHistoryEntry(content, pokemonID)
+}
+
+// listHistoryRecords returns the records of every publish of a pokemon, in
+// publish order.
+func listHistoryRecords(ctx context.Context, bucket cloud.FileBucket, pokemonID int) ([]historyEntry, error) {
+	names, err := bucket.List(ctx, historyRecordsPrefix(pokemonID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the history records of pokemon #%d: %w", pokemonID, err)
+	}
+
+	records := []historyEntry{}
+	for _, name := range names {
+		content, err := bucket.ReadFile(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history record %s: %w", name, err)
+		}
+
+		record, err := decodeHistoryEntry(content, pokemonID)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func decodeHistoryEntry(content []byte, pokemonID int) (historyEntry, error) {
+	entry := historyEntry{PokemonID: pokemonID}
+
+	if len(content) == 0 {
+		return entry, nil
+	}
+
+	if err := json.Unmarshal(content, &entry); err != nil {
+		return entry, fmt.Errorf("history of pokemon #%d is not a valid JSON: %w", pokemonID, err)
+	}
+	if entry.PokemonID == 0 {
+		entry.PokemonID = pokemonID
+	}
+
+	return entry, nil
+}
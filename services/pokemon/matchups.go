@@ -0,0 +1,118 @@
+package pokemon
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// typeOrder is the order types are listed in, matching the games' type
+// chart. Types missing from it are listed after these, alphabetically.
+var typeOrder = []string{
+	"normal", "fire", "water", "electric", "grass", "ice",
+	"fighting", "poison", "ground", "flying", "psychic", "bug",
+	"rock", "ghost", "dragon", "dark", "steel", "fairy",
+}
+
+// defensiveMultipliers combines the damage relations of a pokemon's types
+// into the multiplier each attacking type deals to it. Attacking types that
+// deal neutral damage to every one of its types are left out.
+func defensiveMultipliers(types []RespType) map[string]float64 {
+	multipliers := make(map[string]float64)
+
+	apply := func(relations []Type, factor float64) {
+		for _, relation := range relations {
+			if _, ok := multipliers[relation.Name]; !ok {
+				multipliers[relation.Name] = 1
+			}
+			multipliers[relation.Name] *= factor
+		}
+	}
+
+	for _, pokemonType := range types {
+		apply(pokemonType.DamageRelations.DoubleDamageFrom, 2)
+		apply(pokemonType.DamageRelations.HalfDamageFrom, 0.5)
+		apply(pokemonType.DamageRelations.NoDamageFrom, 0)
+	}
+
+	return multipliers
+}
+
+// sortTypes orders type names by typeOrder.
+func sortTypes(names []string) {
+	rank := func(name string) int {
+		for i, ordered := range typeOrder {
+			if ordered == name {
+				return i
+			}
+		}
+		return len(typeOrder)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if rank(names[i]) != rank(names[j]) {
+			return rank(names[i]) < rank(names[j])
+		}
+		return names[i] < names[j]
+	})
+}
+
+// formatMatchups renders the "Weak to / Resists / Immune to" block, marking
+// double weaknesses and resistances with their multiplier.
+func formatMatchups(multipliers map[string]float64) string {
+	names := make([]string, 0, len(multipliers))
+	for name := range multipliers {
+		names = append(names, name)
+	}
+	sortTypes(names)
+
+	weak, resists, immune := []string{}, []string{}, []string{}
+
+	for _, name := range names {
+		multiplier := multipliers[name]
+		label := formatName(name)
+
+		switch {
+		case multiplier == 0:
+			immune = append(immune, label)
+		case multiplier >= 4:
+			weak = append(weak, label+" (×4)")
+		case multiplier > 1:
+			weak = append(weak, label)
+		case multiplier <= 0.25:
+			resists = append(resists, label+" (×¼)")
+		case multiplier < 1:
+			resists = append(resists, label)
+		}
+	}
+
+	lines := []string{}
+	if len(weak) > 0 {
+		lines = append(lines, "Weak to: "+strings.Join(weak, ", "))
+	}
+	if len(resists) > 0 {
+		lines = append(lines, "Resists: "+strings.Join(resists, ", "))
+	}
+	if len(immune) > 0 {
+		lines = append(lines, "Immune to: "+strings.Join(immune, ", "))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// getMatchups fetches the damage relations of each of the pokemon's types and
+// renders its combined weaknesses, resistances and immunities.
+func getMatchups(ctx context.Context, api PokeAPI, pokemon RespPokemon) (string, error) {
+	types := []RespType{}
+
+	for _, pokemonType := range pokemon.Types {
+		details, err := api.GetType(ctx, pokemonType.Type.Name)
+		if err != nil {
+			return "", fmt.Errorf("failed to get type %s: %w", pokemonType.Type.Name, err)
+		}
+		types = append(types, details)
+	}
+
+	return formatMatchups(defensiveMultipliers(types)), nil
+}
@@ -0,0 +1,78 @@
+package pokemon
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFormatMatchups(t *testing.T) {
+	tests := []struct {
+		name        string
+		multipliers map[string]float64
+		want        string
+	}{
+		{
+			name: "no matchups",
+		},
+		{
+			name:        "neutral types left out",
+			multipliers: map[string]float64{"normal": 1, "fire": 2},
+			want:        "Weak to: Fire",
+		},
+		{
+			name:        "double weakness and resistance",
+			multipliers: map[string]float64{"ice": 4, "rock": 2, "grass": 0.25, "water": 0.5},
+			want:        "Weak to: Ice (×4), Rock\nResists: Water, Grass (×¼)",
+		},
+		{
+			name:        "immunity",
+			multipliers: map[string]float64{"ground": 0, "electric": 0.5},
+			want:        "Resists: Electric\nImmune to: Ground",
+		},
+		{
+			name:        "type chart order",
+			multipliers: map[string]float64{"fairy": 2, "steel": 2, "normal": 2, "shadow": 2, "bug": 2},
+			want:        "Weak to: Normal, Bug, Steel, Fairy, Shadow",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatMatchups(tt.multipliers); got != tt.want {
+				t.Errorf("formatMatchups = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetMatchups(t *testing.T) {
+	types := func(names ...string) []Type {
+		list := []Type{}
+		for _, name := range names {
+			list = append(list, Type{Name: name})
+		}
+		return list
+	}
+
+	api := newFakeAPI()
+	api.types["grass"] = RespType{Name: "grass", DamageRelations: DamageRelations{
+		DoubleDamageFrom: types("flying", "poison", "bug", "fire", "ice"),
+		HalfDamageFrom:   types("ground", "water", "grass", "electric"),
+	}}
+	api.types["poison"] = RespType{Name: "poison", DamageRelations: DamageRelations{
+		DoubleDamageFrom: types("ground", "psychic"),
+		HalfDamageFrom:   types("fighting", "poison", "bug", "grass", "fairy"),
+	}}
+
+	bulbasaur := RespPokemon{Types: []Types{{Slot: 1, Type: Type{Name: "grass"}}, {Slot: 2, Type: Type{Name: "poison"}}}}
+
+	got, err := getMatchups(context.Background(), api, bulbasaur)
+	if err != nil {
+		t.Fatalf("getMatchups failed: %v", err)
+	}
+
+	want := "Weak to: Fire, Ice, Flying, Psychic\nResists: Water, Electric, Grass (×¼), Fighting, Fairy"
+	if got != want {
+		t.Errorf("getMatchups = %q, want %q", got, want)
+	}
+}
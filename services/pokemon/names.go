@@ -0,0 +1,49 @@
+package pokemon
+
+import "context"
+
+// englishName returns the English entry of a localized names array.
+func englishName(names []Names) string {
+	for _, name := range names {
+		if name.Language.Name == "en" {
+			return name.Name
+		}
+	}
+
+	return ""
+}
+
+// getDisplayName returns the official English name of a pokemon, such as
+// "Mr. Mime", "Nidoran♀" or "Type: Null" rather than a title-cased slug. A
+// form's full name, like "Alolan Raichu", takes precedence over the species
+// name, and the slug is used as a last resort.
+func getDisplayName(pokemon RespPokemon, species RespPokemonSpecies, form RespPokemonForm) string {
+	if name := englishName(form.Names); name != "" {
+		return name
+	}
+
+	if name := englishName(species.Names); name != "" {
+		return name
+	}
+
+	return formatName(pokemon.Name)
+}
+
+// lookupDisplayName fetches the species and form of pokemon to name it as its
+// post would. Lookups that fail fall back to the next name getDisplayName
+// tries.
+func lookupDisplayName(ctx context.Context, api PokeAPI, pokemon RespPokemon) string {
+	var species RespPokemonSpecies
+	if speciesID, err := idFromURL(pokemon.Species.URL); err == nil {
+		if fetched, err := api.GetSpecies(ctx, speciesID); err == nil {
+			species = fetched
+		}
+	}
+
+	form, err := getForm(ctx, api, pokemon)
+	if err != nil {
+		form = RespPokemonForm{}
+	}
+
+	return getDisplayName(pokemon, species, form)
+}
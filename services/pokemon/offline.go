@@ -0,0 +1,658 @@
+package pokemon
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// OfflineClient is a PokeAPI implementation backed by the CSV data dump
+// published in the PokeAPI repository (data/v2/csv) and a local copy of the
+// sprites repository, so no network calls are made.
+type OfflineClient struct {
+	pokemon     map[int]RespPokemon
+	species     map[int]RespPokemonSpecies
+	chains      map[int]RespEvolutionChain
+	abilities   map[string]RespAbility
+	forms       map[string]RespPokemonForm
+	types       map[string]RespType
+	generations map[int]RespGeneration
+}
+
+// loadedDumps are the dumps loaded by this process, keyed by their dump and
+// sprite directories, so the CSV files are parsed once rather than on every
+// publish. An OfflineClient is never changed after loading, so it can be
+// shared.
+var (
+	loadedDumpsMu sync.Mutex
+	loadedDumps   = make(map[[2]string]*OfflineClient)
+)
+
+// loadDumpOnce returns the dump in dumpDir, loading it on first use.
+func loadDumpOnce(dumpDir string, spriteDir string) (*OfflineClient, error) {
+	loadedDumpsMu.Lock()
+	defer loadedDumpsMu.Unlock()
+
+	key := [2]string{dumpDir, spriteDir}
+	if client, ok := loadedDumps[key]; ok {
+		return client, nil
+	}
+
+	client, err := LoadDump(dumpDir, spriteDir)
+	if err != nil {
+		return nil, err
+	}
+	loadedDumps[key] = client
+
+	return client, nil
+}
+
+// LoadDump indexes the CSV files in dumpDir. spriteDir is laid out like the
+// PokeAPI sprites repository, i.e. official artwork is expected under
+// pokemon/other/official-artwork/{id}.png.
+func LoadDump(dumpDir string, spriteDir string) (*OfflineClient, error) {
+	client := &OfflineClient{
+		pokemon:     make(map[int]RespPokemon),
+		species:     make(map[int]RespPokemonSpecies),
+		chains:      make(map[int]RespEvolutionChain),
+		abilities:   make(map[string]RespAbility),
+		forms:       make(map[string]RespPokemonForm),
+		types:       make(map[string]RespType),
+		generations: make(map[int]RespGeneration),
+	}
+
+	typeNames, err := loadIdentifiers(filepath.Join(dumpDir, "types.csv"))
+	if err != nil {
+		return nil, err
+	}
+
+	statNames, err := loadIdentifiers(filepath.Join(dumpDir, "stats.csv"))
+	if err != nil {
+		return nil, err
+	}
+
+	languageNames, err := loadIdentifiers(filepath.Join(dumpDir, "languages.csv"))
+	if err != nil {
+		return nil, err
+	}
+
+	versionNames, err := loadIdentifiers(filepath.Join(dumpDir, "versions.csv"))
+	if err != nil {
+		return nil, err
+	}
+
+	varieties := make(map[int][]Varieties)
+
+	err = readCSV(filepath.Join(dumpDir, "pokemon.csv"), func(row map[string]string) error {
+		id, err := strconv.Atoi(row["id"])
+		if err != nil {
+			return err
+		}
+		speciesID, err := strconv.Atoi(row["species_id"])
+		if err != nil {
+			return err
+		}
+
+		varieties[speciesID] = append(varieties[speciesID], Varieties{
+			IsDefault: row["is_default"] == "1",
+			Pokemon:   Pokemon{Name: row["identifier"], URL: fmt.Sprintf("pokemon/%d/", id)},
+		})
+
+		artworkDir := filepath.Join(spriteDir, "pokemon", "other", "official-artwork")
+
+		// like the API, a pokemon without shiny artwork has no shiny sprite,
+		// so a shiny roll falls back to the default artwork.
+		shiny := filepath.Join(artworkDir, "shiny", fmt.Sprintf("%d.png", id))
+		if _, err := os.Stat(shiny); err != nil {
+			shiny = ""
+		}
+
+		client.pokemon[id] = RespPokemon{
+			ID:      id,
+			Name:    row["identifier"],
+			Species: Species{URL: fmt.Sprintf("pokemon-species/%d/", speciesID)},
+			Sprites: Sprites{
+				Other: Other{
+					OfficialArtwork: OfficialArtwork{
+						FrontDefault: filepath.Join(artworkDir, fmt.Sprintf("%d.png", id)),
+						FrontShiny:   shiny,
+					},
+				},
+			},
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	abilityNames, err := loadIdentifiers(filepath.Join(dumpDir, "abilities.csv"))
+	if err != nil {
+		return nil, err
+	}
+
+	err = readCSV(filepath.Join(dumpDir, "pokemon_abilities.csv"), func(row map[string]string) error {
+		id, err := strconv.Atoi(row["pokemon_id"])
+		if err != nil {
+			return err
+		}
+		slot, err := strconv.Atoi(row["slot"])
+		if err != nil {
+			return err
+		}
+
+		pokemon := client.pokemon[id]
+		pokemon.Abilities = append(pokemon.Abilities, Abilities{
+			Ability:  Ability{Name: abilityNames[row["ability_id"]]},
+			IsHidden: row["is_hidden"] == "1",
+			Slot:     slot,
+		})
+		client.pokemon[id] = pokemon
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readCSV(filepath.Join(dumpDir, "ability_prose.csv"), func(row map[string]string) error {
+		name := abilityNames[row["ability_id"]]
+
+		ability := client.abilities[name]
+		ability.Name = name
+		ability.EffectEntries = append(ability.EffectEntries, EffectEntries{
+			Effect:      row["effect"],
+			ShortEffect: row["short_effect"],
+			Language:    Language{Name: languageNames[row["local_language_id"]]},
+		})
+		client.abilities[name] = ability
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readCSV(filepath.Join(dumpDir, "pokemon_types.csv"), func(row map[string]string) error {
+		id, err := strconv.Atoi(row["pokemon_id"])
+		if err != nil {
+			return err
+		}
+		slot, err := strconv.Atoi(row["slot"])
+		if err != nil {
+			return err
+		}
+
+		pokemon := client.pokemon[id]
+		pokemon.Types = append(pokemon.Types, Types{
+			Slot: slot,
+			Type: Type{Name: typeNames[row["type_id"]]},
+		})
+		client.pokemon[id] = pokemon
+
+		typeName := typeNames[row["type_id"]]
+		pokemonType := client.types[typeName]
+		pokemonType.Name = typeName
+		pokemonType.Pokemon = append(pokemonType.Pokemon, TypePokemon{
+			Slot:    slot,
+			Pokemon: Pokemon{Name: pokemon.Name, URL: fmt.Sprintf("pokemon/%d/", id)},
+		})
+		client.types[typeName] = pokemonType
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readCSV(filepath.Join(dumpDir, "pokemon_stats.csv"), func(row map[string]string) error {
+		id, err := strconv.Atoi(row["pokemon_id"])
+		if err != nil {
+			return err
+		}
+		baseStat, err := strconv.Atoi(row["base_stat"])
+		if err != nil {
+			return err
+		}
+		effort, err := strconv.Atoi(row["effort"])
+		if err != nil {
+			return err
+		}
+
+		pokemon := client.pokemon[id]
+		pokemon.Stats = append(pokemon.Stats, Stats{
+			BaseStat: baseStat,
+			Effort:   effort,
+			Stat:     Stat{Name: statNames[row["stat_id"]]},
+		})
+		client.pokemon[id] = pokemon
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	speciesNodes := make(map[int]*dumpSpecies)
+	speciesIDs := []int{}
+
+	err = readCSV(filepath.Join(dumpDir, "pokemon_species.csv"), func(row map[string]string) error {
+		id, err := strconv.Atoi(row["id"])
+		if err != nil {
+			return err
+		}
+		chainID, err := strconv.Atoi(row["evolution_chain_id"])
+		if err != nil {
+			return err
+		}
+		// the root of a chain has no species it evolves from
+		evolvesFrom, _ := strconv.Atoi(row["evolves_from_species_id"])
+
+		speciesNodes[id] = &dumpSpecies{
+			name:        row["identifier"],
+			chainID:     chainID,
+			evolvesFrom: evolvesFrom,
+		}
+		speciesIDs = append(speciesIDs, id)
+
+		generationID, err := strconv.Atoi(row["generation_id"])
+		if err != nil {
+			return err
+		}
+		generation := client.generations[generationID]
+		generation.ID = generationID
+		generation.PokemonSpecies = append(generation.PokemonSpecies, Species{
+			Name: row["identifier"],
+			URL:  fmt.Sprintf("pokemon-species/%d/", id),
+		})
+		client.generations[generationID] = generation
+
+		client.species[id] = RespPokemonSpecies{
+			Name:           row["identifier"],
+			IsLegendary:    row["is_legendary"] == "1",
+			IsMythical:     row["is_mythical"] == "1",
+			EvolutionChain: EvolutionChain{URL: fmt.Sprintf("evolution-chain/%d/", chainID)},
+			Varieties:      varieties[id],
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.loadEvolutions(dumpDir, speciesNodes, speciesIDs, typeNames); err != nil {
+		return nil, err
+	}
+
+	err = readCSV(filepath.Join(dumpDir, "pokemon_species_flavor_text.csv"), func(row map[string]string) error {
+		id, err := strconv.Atoi(row["species_id"])
+		if err != nil {
+			return err
+		}
+
+		species := client.species[id]
+		species.FlavorTextEntries = append(species.FlavorTextEntries, FlavorTextEntries{
+			FlavorText: row["flavor_text"],
+			Language:   Language{Name: languageNames[row["language_id"]]},
+			Version:    Version{Name: versionNames[row["version_id"]]},
+		})
+		client.species[id] = species
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := client.loadNames(dumpDir, languageNames); err != nil {
+		return nil, err
+	}
+
+	if err := client.loadTypeEfficacy(dumpDir, typeNames); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// loadNames indexes the localized species names and the pokemon forms along
+// with their localized names.
+func (c *OfflineClient) loadNames(dumpDir string, languageNames map[string]string) error {
+	err := readCSV(filepath.Join(dumpDir, "pokemon_species_names.csv"), func(row map[string]string) error {
+		id, err := strconv.Atoi(row["pokemon_species_id"])
+		if err != nil {
+			return err
+		}
+
+		species := c.species[id]
+		species.Names = append(species.Names, Names{
+			Name:     row["name"],
+			Language: Language{Name: languageNames[row["local_language_id"]]},
+		})
+		c.species[id] = species
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	formIdentifiers := make(map[string]string)
+
+	err = readCSV(filepath.Join(dumpDir, "pokemon_forms.csv"), func(row map[string]string) error {
+		pokemonID, err := strconv.Atoi(row["pokemon_id"])
+		if err != nil {
+			return err
+		}
+
+		formIdentifiers[row["id"]] = row["identifier"]
+
+		c.forms[row["identifier"]] = RespPokemonForm{
+			Name:      row["identifier"],
+			FormName:  row["form_identifier"],
+			IsDefault: row["is_default"] == "1",
+		}
+
+		pokemon := c.pokemon[pokemonID]
+		pokemon.Forms = append(pokemon.Forms, Form{Name: row["identifier"]})
+		c.pokemon[pokemonID] = pokemon
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return readCSV(filepath.Join(dumpDir, "pokemon_form_names.csv"), func(row map[string]string) error {
+		identifier := formIdentifiers[row["pokemon_form_id"]]
+		language := Language{Name: languageNames[row["local_language_id"]]}
+
+		form := c.forms[identifier]
+		if row["pokemon_name"] != "" {
+			form.Names = append(form.Names, Names{Name: row["pokemon_name"], Language: language})
+		}
+		if row["form_name"] != "" {
+			form.FormNames = append(form.FormNames, Names{Name: row["form_name"], Language: language})
+		}
+		c.forms[identifier] = form
+		return nil
+	})
+}
+
+func (c *OfflineClient) GetPokemon(ctx context.Context, id int) (RespPokemon, error) {
+	pokemon, ok := c.pokemon[id]
+	if !ok {
+		return pokemon, fmt.Errorf("pokemon %d not found in data dump", id)
+	}
+
+	return pokemon, nil
+}
+
+func (c *OfflineClient) GetPokemonByName(ctx context.Context, name string) (RespPokemon, error) {
+	for _, pokemon := range c.pokemon {
+		if pokemon.Name == name {
+			return pokemon, nil
+		}
+	}
+
+	return RespPokemon{}, fmt.Errorf("pokemon %s not found in data dump", name)
+}
+
+func (c *OfflineClient) GetEvolutionChain(ctx context.Context, id int) (RespEvolutionChain, error) {
+	chain, ok := c.chains[id]
+	if !ok {
+		return chain, fmt.Errorf("evolution chain %d not found in data dump", id)
+	}
+
+	return chain, nil
+}
+
+func (c *OfflineClient) GetAbility(ctx context.Context, name string) (RespAbility, error) {
+	ability, ok := c.abilities[name]
+	if !ok {
+		return ability, fmt.Errorf("ability %s not found in data dump", name)
+	}
+
+	return ability, nil
+}
+
+func (c *OfflineClient) GetPokemonForm(ctx context.Context, name string) (RespPokemonForm, error) {
+	form, ok := c.forms[name]
+	if !ok {
+		return form, fmt.Errorf("pokemon form %s not found in data dump", name)
+	}
+
+	return form, nil
+}
+
+func (c *OfflineClient) GetType(ctx context.Context, name string) (RespType, error) {
+	pokemonType, ok := c.types[name]
+	if !ok {
+		return pokemonType, fmt.Errorf("type %s not found in data dump", name)
+	}
+
+	return pokemonType, nil
+}
+
+func (c *OfflineClient) GetSpeciesCount(ctx context.Context) (int, error) {
+	if len(c.species) == 0 {
+		return 0, fmt.Errorf("no species found in data dump")
+	}
+
+	return len(c.species), nil
+}
+
+func (c *OfflineClient) GetGeneration(ctx context.Context, id int) (RespGeneration, error) {
+	generation, ok := c.generations[id]
+	if !ok {
+		return generation, fmt.Errorf("generation %d not found in data dump", id)
+	}
+
+	return generation, nil
+}
+
+func (c *OfflineClient) GetSpecies(ctx context.Context, id int) (RespPokemonSpecies, error) {
+	species, ok := c.species[id]
+	if !ok {
+		return species, fmt.Errorf("pokemon species %d not found in data dump", id)
+	}
+
+	return species, nil
+}
+
+// GetSprite reads the sprite from the local sprite directory. url is the path
+// set on the sprites returned by GetPokemon.
+func (c *OfflineClient) GetSprite(ctx context.Context, url string) ([]byte, error) {
+	sprite, err := os.ReadFile(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sprite for pokemon: %w", err)
+	}
+
+	return sprite, nil
+}
+
+// dumpSpecies is the part of a pokemon_species.csv row needed to rebuild
+// evolution chains.
+type dumpSpecies struct {
+	name        string
+	chainID     int
+	evolvesFrom int
+	details     []EvolutionDetails
+}
+
+// loadEvolutions rebuilds the evolution-chain resources from the species
+// tree and pokemon_evolution.csv. speciesIDs is in dump order, which keeps
+// branches in the same order as the API.
+func (c *OfflineClient) loadEvolutions(dumpDir string, speciesNodes map[int]*dumpSpecies, speciesIDs []int, typeNames map[string]string) error {
+	triggerNames, err := loadIdentifiers(filepath.Join(dumpDir, "evolution_triggers.csv"))
+	if err != nil {
+		return err
+	}
+
+	itemNames, err := loadIdentifiers(filepath.Join(dumpDir, "items.csv"))
+	if err != nil {
+		return err
+	}
+
+	locationNames, err := loadIdentifiers(filepath.Join(dumpDir, "locations.csv"))
+	if err != nil {
+		return err
+	}
+
+	err = readCSV(filepath.Join(dumpDir, "pokemon_evolution.csv"), func(row map[string]string) error {
+		id, err := strconv.Atoi(row["evolved_species_id"])
+		if err != nil {
+			return err
+		}
+
+		node, ok := speciesNodes[id]
+		if !ok {
+			return fmt.Errorf("unknown species %d", id)
+		}
+
+		details := EvolutionDetails{
+			Trigger:   EvolutionTrigger{Name: triggerNames[row["evolution_trigger_id"]]},
+			TimeOfDay: row["time_of_day"],
+		}
+		details.MinLevel, _ = strconv.Atoi(row["minimum_level"])
+		details.MinHappiness, _ = strconv.Atoi(row["minimum_happiness"])
+		details.MinAffection, _ = strconv.Atoi(row["minimum_affection"])
+
+		if name, ok := itemNames[row["trigger_item_id"]]; ok {
+			details.Item = &Item{Name: name}
+		}
+		if name, ok := itemNames[row["held_item_id"]]; ok {
+			details.HeldItem = &Item{Name: name}
+		}
+		if name, ok := typeNames[row["known_move_type_id"]]; ok {
+			details.KnownMoveType = &Type{Name: name}
+		}
+		if name, ok := locationNames[row["location_id"]]; ok {
+			details.Location = &Location{Name: name}
+		}
+		if tradeSpecies, err := strconv.Atoi(row["trade_species_id"]); err == nil {
+			if trade, ok := speciesNodes[tradeSpecies]; ok {
+				details.TradeSpecies = &Species{Name: trade.name}
+			}
+		}
+
+		node.details = append(node.details, details)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	children := make(map[int][]int)
+	for _, id := range speciesIDs {
+		if from := speciesNodes[id].evolvesFrom; from != 0 {
+			children[from] = append(children[from], id)
+		}
+	}
+
+	var link func(id int) ChainLink
+	link = func(id int) ChainLink {
+		node := speciesNodes[id]
+		chainLink := ChainLink{
+			Species:          Species{Name: node.name, URL: fmt.Sprintf("pokemon-species/%d/", id)},
+			EvolutionDetails: node.details,
+			EvolvesTo:        []ChainLink{},
+		}
+		for _, child := range children[id] {
+			chainLink.EvolvesTo = append(chainLink.EvolvesTo, link(child))
+		}
+		return chainLink
+	}
+
+	for _, id := range speciesIDs {
+		node := speciesNodes[id]
+		if node.evolvesFrom == 0 {
+			c.chains[node.chainID] = RespEvolutionChain{ID: node.chainID, Chain: link(id)}
+		}
+	}
+
+	return nil
+}
+
+// loadTypeEfficacy builds the damage relations of every type from the damage
+// factors (as percentages) in type_efficacy.csv.
+func (c *OfflineClient) loadTypeEfficacy(dumpDir string, typeNames map[string]string) error {
+	return readCSV(filepath.Join(dumpDir, "type_efficacy.csv"), func(row map[string]string) error {
+		attacking := typeNames[row["damage_type_id"]]
+		defending := typeNames[row["target_type_id"]]
+
+		attacker := c.types[attacking]
+		attacker.Name = attacking
+		switch row["damage_factor"] {
+		case "200":
+			attacker.DamageRelations.DoubleDamageTo = append(attacker.DamageRelations.DoubleDamageTo, Type{Name: defending})
+		case "50":
+			attacker.DamageRelations.HalfDamageTo = append(attacker.DamageRelations.HalfDamageTo, Type{Name: defending})
+		case "0":
+			attacker.DamageRelations.NoDamageTo = append(attacker.DamageRelations.NoDamageTo, Type{Name: defending})
+		}
+		c.types[attacking] = attacker
+
+		defender := c.types[defending]
+		defender.Name = defending
+		switch row["damage_factor"] {
+		case "200":
+			defender.DamageRelations.DoubleDamageFrom = append(defender.DamageRelations.DoubleDamageFrom, Type{Name: attacking})
+		case "50":
+			defender.DamageRelations.HalfDamageFrom = append(defender.DamageRelations.HalfDamageFrom, Type{Name: attacking})
+		case "0":
+			defender.DamageRelations.NoDamageFrom = append(defender.DamageRelations.NoDamageFrom, Type{Name: attacking})
+		}
+		c.types[defending] = defender
+		return nil
+	})
+}
+
+// loadIdentifiers maps the id column of a CSV file to its identifier column.
+func loadIdentifiers(path string) (map[string]string, error) {
+	identifiers := make(map[string]string)
+
+	err := readCSV(path, func(row map[string]string) error {
+		identifiers[row["id"]] = row["identifier"]
+		return nil
+	})
+
+	return identifiers, err
+}
+
+// readCSV calls fn for every row of the CSV file at path, keyed by the names
+// in its header row.
+func readCSV(path string, fn func(row map[string]string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open data dump file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read header of %s: %w", path, err)
+	}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		row := make(map[string]string, len(header))
+		for i, column := range header {
+			if i < len(record) {
+				row[column] = record[i]
+			}
+		}
+
+		if err := fn(row); err != nil {
+			return fmt.Errorf("invalid row on line %d of %s: %w", line, path, err)
+		}
+	}
+}
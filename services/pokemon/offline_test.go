@@ -0,0 +1,102 @@
+package pokemon
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeDump writes a data dump with the pokemon 25 and 26, of which only 25
+// has shiny artwork.
+func writeDump(t *testing.T) (string, string) {
+	t.Helper()
+
+	dumpDir, spriteDir := t.TempDir(), t.TempDir()
+
+	files := map[string]string{
+		"types.csv":                       "id,identifier\n13,electric\n",
+		"stats.csv":                       "id,identifier\n",
+		"languages.csv":                   "id,identifier\n9,en\n",
+		"versions.csv":                    "id,identifier\n",
+		"pokemon.csv":                     "id,identifier,species_id,is_default\n25,pikachu,25,1\n26,raichu,26,1\n",
+		"abilities.csv":                   "id,identifier\n",
+		"pokemon_abilities.csv":           "pokemon_id,ability_id,is_hidden,slot\n",
+		"ability_prose.csv":               "ability_id,local_language_id,short_effect,effect\n",
+		"pokemon_types.csv":               "pokemon_id,type_id,slot\n25,13,1\n26,13,1\n",
+		"pokemon_stats.csv":               "pokemon_id,stat_id,base_stat,effort\n",
+		"pokemon_species.csv":             "id,identifier,generation_id,evolves_from_species_id,evolution_chain_id,is_legendary,is_mythical\n25,pikachu,1,,10,0,0\n26,raichu,1,25,10,0,0\n",
+		"pokemon_species_flavor_text.csv": "species_id,version_id,language_id,flavor_text\n",
+		"pokemon_species_names.csv":       "pokemon_species_id,local_language_id,name,genus\n",
+		"pokemon_forms.csv":               "id,identifier,form_identifier,pokemon_id,is_default\n",
+		"pokemon_form_names.csv":          "pokemon_form_id,local_language_id,form_name,pokemon_name\n",
+		"evolution_triggers.csv":          "id,identifier\n",
+		"items.csv":                       "id,identifier\n",
+		"locations.csv":                   "id,identifier\n",
+		"pokemon_evolution.csv":           "id,evolved_species_id,evolution_trigger_id,minimum_level\n",
+		"type_efficacy.csv":               "damage_type_id,target_type_id,damage_factor\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dumpDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	shinyDir := filepath.Join(spriteDir, "pokemon", "other", "official-artwork", "shiny")
+	if err := os.MkdirAll(shinyDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(shinyDir, "25.png"), []byte("png"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return dumpDir, spriteDir
+}
+
+func TestLoadDumpShinyArtwork(t *testing.T) {
+	dumpDir, spriteDir := writeDump(t)
+
+	client, err := LoadDump(dumpDir, spriteDir)
+	if err != nil {
+		t.Fatalf("LoadDump failed: %v", err)
+	}
+
+	tests := []struct {
+		id        int
+		wantShiny bool
+	}{
+		{id: 25, wantShiny: true},
+		{id: 26, wantShiny: false},
+	}
+
+	for _, tt := range tests {
+		pokemon, err := client.GetPokemon(context.Background(), tt.id)
+		if err != nil {
+			t.Fatalf("GetPokemon(%d) failed: %v", tt.id, err)
+		}
+		if hasShiny := pokemon.Sprites.Other.OfficialArtwork.FrontShiny != ""; hasShiny != tt.wantShiny {
+			t.Errorf("pokemon %d has shiny artwork = %v, want %v", tt.id, hasShiny, tt.wantShiny)
+		}
+	}
+}
+
+func TestLoadDumpOnce(t *testing.T) {
+	dumpDir, spriteDir := writeDump(t)
+
+	first, err := loadDumpOnce(dumpDir, spriteDir)
+	if err != nil {
+		t.Fatalf("loadDumpOnce failed: %v", err)
+	}
+
+	// a second load must not read the files again
+	if err := os.RemoveAll(dumpDir); err != nil {
+		t.Fatal(err)
+	}
+	second, err := loadDumpOnce(dumpDir, spriteDir)
+	if err != nil {
+		t.Fatalf("second loadDumpOnce failed: %v", err)
+	}
+	if first != second {
+		t.Errorf("loadDumpOnce loaded the dump twice")
+	}
+}
@@ -0,0 +1,167 @@
+package pokemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Override publishes a specific pokemon on demand, e.g. for announcements,
+// instead of the day's pick.
+type Override struct {
+	// ID is a PokeAPI pokemon ID and Name its name, e.g. 25 or "pikachu".
+	// Exactly one of them is set.
+	ID   int
+	Name string
+
+	// Form picks a variety of the species, e.g. "alola" or "gmax".
+	Form string
+
+	Shiny bool
+
+	// Force publishes the pokemon even if it has been published before.
+	Force bool
+}
+
+// Validate checks that o names a pokemon by exactly one of ID and Name, and
+// that Name and Form are spelled as PokeAPI names.
+func (o Override) Validate() error {
+	if (o.ID == 0) == (o.Name == "") {
+		return fmt.Errorf("exactly one of id and name must be set")
+	}
+	if o.ID < 0 {
+		return fmt.Errorf("id must be a positive number, got %d", o.ID)
+	}
+	if o.Name != "" {
+		if err := validName("pokemon", o.name()); err != nil {
+			return err
+		}
+	}
+	if o.Form != "" {
+		if err := validName("form", o.form()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolve returns the pokemon ID the override refers to and its species.
+func (o Override) resolve(ctx context.Context, api PokeAPI) (int, int, error) {
+	var pokemon RespPokemon
+	var err error
+	if o.ID != 0 {
+		pokemon, err = api.GetPokemon(ctx, o.ID)
+	} else {
+		pokemon, err = api.GetPokemonByName(ctx, o.name())
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to find pokemon %s: %w", o, err)
+	}
+
+	id := pokemon.ID
+	if id == 0 {
+		id = o.ID
+	}
+
+	speciesID, err := idFromURL(pokemon.Species.URL)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get species of pokemon %s: %w", o, err)
+	}
+
+	if o.Form == "" {
+		return id, speciesID, nil
+	}
+
+	species, err := api.GetSpecies(ctx, speciesID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get varieties of pokemon %s: %w", o, err)
+	}
+
+	form := o.form()
+	varieties := []string{}
+	for _, variety := range species.Varieties {
+		if variety.Pokemon.Name == form || variety.Pokemon.Name == species.Name+"-"+form {
+			id, err := idFromURL(variety.Pokemon.URL)
+			return id, speciesID, err
+		}
+		varieties = append(varieties, variety.Pokemon.Name)
+	}
+
+	return 0, 0, fmt.Errorf("%s has no form %q; its varieties are %s", species.Name, o.Form, strings.Join(varieties, ", "))
+}
+
+// name and form are the name and form as PokeAPI spells them.
+func (o Override) name() string {
+	return strings.ToLower(strings.TrimSpace(o.Name))
+}
+
+func (o Override) form() string {
+	return strings.ToLower(strings.TrimSpace(o.Form))
+}
+
+func (o Override) String() string {
+	name := o.Name
+	if o.ID != 0 {
+		name = fmt.Sprintf("#%d", o.ID)
+	}
+	if o.Form != "" {
+		name += fmt.Sprintf(" (%s)", o.Form)
+	}
+
+	return name
+}
+
+// override plans the publish of the pokemon requested by o on the day of
+// now. The selector is left out, so the day's pick is still published by the
+// next scheduled run.
+func (p *publisher) override(ctx context.Context, now time.Time, o Override) (plan, error) {
+	if err := o.Validate(); err != nil {
+		return plan{}, fmt.Errorf("invalid override: %w", err)
+	}
+
+	pokemonID, speciesID, err := o.resolve(ctx, p.api)
+	if err != nil {
+		return plan{}, err
+	}
+
+	if !o.Force {
+		published, err := p.state.isPublished(ctx, pokemonID)
+		if err != nil {
+			return plan{}, fmt.Errorf("failed to check if pokemon #%d has been published already: %w", pokemonID, err)
+		}
+		if published {
+			return plan{}, fmt.Errorf("pokemon #%d has been published already; use force to publish it again", pokemonID)
+		}
+	}
+
+	return plan{
+		Date:      p.schedule.date(now),
+		Selection: Selection{SpeciesID: speciesID},
+		PokemonID: pokemonID,
+		Shiny:     o.Shiny,
+	}, nil
+}
+
+// planFor plans the override in opts if there is one and the day's pick
+// otherwise. Errors that still leave a usable plan are only logged.
+func (p *publisher) planFor(ctx context.Context, now time.Time, opts PublishOptions) (plan, error) {
+	if opts.Override != nil {
+		return p.override(ctx, now, *opts.Override)
+	}
+
+	planned, err := p.plan(ctx, now)
+	if err != nil {
+		if planned.PokemonID == 0 {
+			return planned, err
+		}
+		logger := zerolog.New(os.Stdout)
+		logger.Err(err).Msgf("publishing pokemon #%d anyway", planned.PokemonID)
+	}
+
+	return planned, nil
+}
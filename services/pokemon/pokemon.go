@@ -2,108 +2,109 @@ package pokemon
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"os"
-	"reflect"
 	"strings"
 	"time"
 
-	"github.com/go-resty/resty/v2"
 	"github.com/rickrollrumble/random-pokemon-publisher/services/bluesky"
-	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud/gcp"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cache"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
 	"github.com/rs/zerolog"
 	"github.com/vicanso/go-charts/v2"
-	"golang.org/x/exp/rand"
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
 )
 
-func getPokemon(id int) (RespPokemon, error) {
-	client := resty.New().SetBaseURL("https://pokeapi.co/api/v2")
-
-	req := client.R()
-
-	var pokemon RespPokemon
-
-	resp, err := req.Get(fmt.Sprintf("pokemon/%d", id))
-	if err != nil {
-		return pokemon, fmt.Errorf("failed to make request to fetch pokemon: %w", err)
-	}
-
-	if resp.IsError() {
-		return pokemon, fmt.Errorf("received an error while trying to fetch pokemon")
-	}
+func getFlavorText(species RespPokemonSpecies) (string, error) {
+	flavorText := ""
 
-	unmarshalErr := json.Unmarshal(resp.Body(), &pokemon)
-	if unmarshalErr != nil {
-		return pokemon, fmt.Errorf("fetched GET pokemon response is not a valid JSON: %w", unmarshalErr)
+	for _, flavorTextEntry := range species.FlavorTextEntries {
+		if flavorTextEntry.Language.Name == "en" {
+			flavorText = flavorTextEntry.FlavorText
+			break
+		}
 	}
 
-	if reflect.ValueOf(pokemon).IsZero() {
-		return pokemon, fmt.Errorf("could not populate pokemon data from response")
+	if flavorText == "" {
+		return flavorText, fmt.Errorf("English flavor text not found for this species")
 	}
 
-	return pokemon, nil
+	return flavorText, nil
 }
 
-func getFlavorText(id int) (string, error) {
-	client := resty.New().SetBaseURL("https://pokeapi.co/api/v2")
+// defaultIntro starts the post on days without a themed intro.
+const defaultIntro = "Today's #Pokemon of the day is"
 
-	req := client.R()
+// postOptions changes how a pokemon is presented.
+type postOptions struct {
+	Shiny bool
 
-	flavorText := ""
+	// Theme is the calendar theme of the day, if any.
+	Theme *Theme
+}
 
-	resp, err := req.Get(fmt.Sprintf("pokemon-species/%d", id))
-	if err != nil {
-		return flavorText, fmt.Errorf("failed to make request to get flavor text of pokemon: %w", err)
-	}
+// draft is a rendered post whose images have not been uploaded yet.
+type draft struct {
+	// Name is the display name of the pokemon and Form its form name, e.g.
+	// "alola", if it is not the default form.
+	Name string
+	Form string
 
-	if resp.IsError() {
-		return flavorText, fmt.Errorf("received an error while trying to fetch flavor text")
-	}
+	// Shiny is set when the shiny artwork is featured, which is not the
+	// case when a shiny was rolled for a pokemon without shiny artwork.
+	Shiny bool
 
-	var speciesResp RespPokemonSpecies
+	// Posts are the text of the first post followed by its replies.
+	Posts  []string
+	Images []DraftImage
+}
 
-	unmarshalErr := json.Unmarshal(resp.Body(), &speciesResp)
+// DraftImage is an image attached to the first post.
+type DraftImage struct {
+	Name     string `json:"name"`
+	Alt      string `json:"alt"`
+	MimeType string `json:"mime_type"`
+	Content  []byte `json:"content"`
+}
 
-	if unmarshalErr != nil {
-		return flavorText, fmt.Errorf("fetched GET pokemon species response is not a valid JSON: %w", unmarshalErr)
-	}
+// renderPost builds the text and images of the post about pokemon id without
+// talking to Bluesky.
+func renderPost(ctx context.Context, api PokeAPI, id int, opts postOptions) (draft, error) {
+	shiny := opts.Shiny
+	logger := zerolog.New(os.Stdout)
 
-	if reflect.ValueOf(speciesResp).IsZero() {
-		return flavorText, fmt.Errorf("could not populate species from response")
+	pokemon, err := api.GetPokemon(ctx, id)
+	if err != nil {
+		return draft{}, fmt.Errorf("failed to get pokemon %d: %w", id, err)
 	}
 
-	for _, flavorTextEntry := range speciesResp.FlavorTextEntries {
-		if flavorTextEntry.Language.Name == "en" {
-			flavorText = flavorTextEntry.FlavorText
-			break
-		}
+	// forms have their own pokemon ID, so the species is looked up from the
+	// pokemon rather than by id.
+	speciesID, speciesIDErr := idFromURL(pokemon.Species.URL)
+	if speciesIDErr != nil {
+		return draft{}, fmt.Errorf("failed to get species of pokemon %d: %w", id, speciesIDErr)
 	}
 
-	if flavorText == "" {
-		return flavorText, fmt.Errorf("English flavor text not found for this species")
+	species, speciesErr := api.GetSpecies(ctx, speciesID)
+	if speciesErr != nil {
+		return draft{}, fmt.Errorf("failed to get species for pokemon %d: %w", id, speciesErr)
 	}
 
-	return flavorText, nil
-}
-
-func getSprite(imageUrl string) ([]byte, error) {
-	resp, err := resty.New().R().Get(imageUrl)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch sprite for pokemon: %w", err)
+	// retries on the same day roll shiny again, so a missing shiny artwork
+	// must not fail the publish.
+	if shiny && pokemon.Sprites.Other.OfficialArtwork.FrontShiny == "" {
+		logger.Warn().Msgf("pokemon %d has no shiny artwork; posting the default artwork instead", id)
+		shiny = false
 	}
 
-	if resp.IsError() {
-		return nil, fmt.Errorf("received an error while fetching pokemon sprite")
+	form, formErr := getForm(ctx, api, pokemon)
+	if formErr != nil {
+		logger.Err(formErr).Msgf("failed to get form of pokemon %d; posting without form name", id)
 	}
 
-	return resp.Body(), nil
-}
+	name := getDisplayName(pokemon, species, form)
 
-func createPost(id int) error {
-	pokemon, err := getPokemon(id)
 	types := []string{}
 
 	titleCaser := cases.Title(language.Und)
@@ -112,10 +113,33 @@ func createPost(id int) error {
 		types = append(types, titleCaser.String(strings.ToLower(pokemonType.Type.Name)))
 	}
 
-	postText := fmt.Sprintf("Today's #Pokemon of the day is %s\n\nType: %s\n\n",
-		titleCaser.String(strings.ToLower(pokemon.Name)),
+	intro := defaultIntro
+	if opts.Theme != nil && opts.Theme.Intro != "" {
+		intro = opts.Theme.Intro
+	}
+
+	postText := fmt.Sprintf("%s %s\n\nType: %s",
+		intro,
+		name,
 		strings.Join(types[:], "/"),
 	)
+	if shiny {
+		postText = fmt.Sprintf("✨ %s a shiny %s! #Shiny\n\nType: %s",
+			intro,
+			name,
+			strings.Join(types[:], "/"),
+		)
+	}
+	if label := formLabel(form); label != "" {
+		postText += fmt.Sprintf("\nForm: %s", label)
+	}
+	if opts.Theme != nil && len(opts.Theme.Hashtags) > 0 {
+		hashtags := []string{}
+		for _, hashtag := range opts.Theme.Hashtags {
+			hashtags = append(hashtags, "#"+strings.TrimPrefix(hashtag, "#"))
+		}
+		postText += "\n" + strings.Join(hashtags, " ")
+	}
 
 	stats := make(map[string]float64)
 
@@ -123,106 +147,297 @@ func createPost(id int) error {
 		stats[pokemonStat.Stat.Name] = float64(pokemonStat.BaseStat)
 	}
 
-	statsChart, statChartErr := createStatsChart(stats, pokemon.Name)
+	statsChart, statChartErr := createStatsChart(stats, name)
 	if statChartErr != nil {
-		return fmt.Errorf("failed to upload stats chart: %w", statChartErr)
+		return draft{}, statChartErr
 	}
 
-	flavorText, flavorTextErr := getFlavorText(id)
+	flavorText, flavorTextErr := getFlavorText(species)
 	if flavorTextErr != nil {
-		return fmt.Errorf("failed to get flavor text for pokemon %d: %w", id, flavorTextErr)
+		return draft{}, fmt.Errorf("failed to get flavor text for pokemon %d: %w", id, flavorTextErr)
 	}
 
-	postText += flavorText
+	sections := []string{postText, flavorText}
 
-	post := bluesky.PostParams{
-		Text: postText,
+	matchups, matchupsErr := getMatchups(ctx, api, pokemon)
+	if matchupsErr != nil {
+		logger.Err(matchupsErr).Msgf("failed to get type matchups of pokemon %d; posting without them", id)
+	} else if matchups != "" {
+		sections = append(sections, matchups)
 	}
 
-	sprite, err := formatSprite(pokemon.Sprites.Other.OfficialArtwork.FrontDefault)
+	abilities, abilitiesErr := getAbilities(ctx, api, pokemon)
+	if abilitiesErr != nil {
+		logger.Err(abilitiesErr).Msgf("failed to describe abilities of pokemon %d; posting without their effects", id)
+	}
+	if abilities != "" {
+		sections = append(sections, abilities)
+	}
+
+	evolution, evolutionErr := getEvolution(ctx, api, species)
+	if evolutionErr != nil {
+		logger.Err(evolutionErr).Msgf("failed to get evolution chain for pokemon %d; posting without it", id)
+	} else if evolution != "" {
+		sections = append(sections, evolution)
+	}
+
+	artwork := pokemon.Sprites.Other.OfficialArtwork.FrontDefault
+	artworkAlt := fmt.Sprintf("official artwork of the pokemon %s", name)
+	if shiny {
+		artwork = pokemon.Sprites.Other.OfficialArtwork.FrontShiny
+		artworkAlt = fmt.Sprintf("official shiny artwork of the pokemon %s, shown in its rare alternate colors", name)
+	}
+
+	sprite, err := formatSprite(ctx, api, artwork)
 	if err != nil {
-		return fmt.Errorf("failed to fetch sprite for pokemon: %w", err)
+		return draft{}, fmt.Errorf("failed to fetch sprite for pokemon: %w", err)
 	}
 
-	post.Images = []bluesky.ImageDetails{
-		{
-			Alt:   fmt.Sprintf("official artwork of the pokemon %s", titleCaser.String(strings.ToLower(pokemon.Name))),
-			Image: sprite,
-		},
-		{
-			Alt:   fmt.Sprintf("radar chart of the stats of the pokemon %s", titleCaser.String(strings.ToLower(pokemon.Name))),
-			Image: statsChart,
+	formName := ""
+	if !form.IsDefault {
+		formName = form.FormName
+	}
+
+	return draft{
+		Name:  name,
+		Form:  formName,
+		Shiny: shiny,
+		// whatever does not fit in the first post is continued in replies.
+		Posts: bluesky.SplitIntoPosts(sections),
+		Images: []DraftImage{
+			{
+				Name:     "artwork.png",
+				Alt:      artworkAlt,
+				MimeType: "image/png",
+				Content:  sprite,
+			},
+			{
+				Name:     "stats.svg",
+				Alt:      fmt.Sprintf("radar chart of the stats of the pokemon %s", name),
+				MimeType: "image/svg+xml",
+				Content:  statsChart,
+			},
 		},
+	}, nil
+}
+
+// sentPost is what Bluesky returned for a published draft.
+type sentPost struct {
+	Post   bluesky.RespCreatePost
+	Images []bluesky.RespImageUpload
+}
+
+// sendPost uploads the images of the draft and publishes it as a thread.
+func sendPost(ctx context.Context, rendered draft) (sentPost, error) {
+	var sent sentPost
+
+	post := bluesky.PostParams{
+		Text: rendered.Posts[0],
+	}
+
+	for _, image := range rendered.Images {
+		uploadedImage, err := bluesky.UploadImage(ctx, image.Content)
+		if err != nil {
+			return sent, fmt.Errorf("failed to upload %s: %w", image.Name, err)
+		}
+
+		sent.Images = append(sent.Images, uploadedImage)
+		post.Images = append(post.Images, bluesky.ImageDetails{
+			Alt:   image.Alt,
+			Image: uploadedImage,
+		})
+	}
+
+	root, err := bluesky.SendThread(ctx, post, rendered.Posts[1:])
+	if err != nil && root.URI == "" {
+		return sent, err
 	}
+	sent.Post = root
 
-	return bluesky.SendPost(context.Background(), post)
+	// a reply that failed to send leaves the first post published, which
+	// still needs to be recorded.
+	return sent, err
 }
 
-func formatSprite(url string) (bluesky.RespImageUpload, error) {
-	resp, err := resty.New().R().Get(url)
+func getEvolution(ctx context.Context, api PokeAPI, species RespPokemonSpecies) (string, error) {
+	if species.EvolutionChain.URL == "" {
+		return "", nil
+	}
+
+	chainID, err := idFromURL(species.EvolutionChain.URL)
+	if err != nil {
+		return "", err
+	}
+
+	chain, err := api.GetEvolutionChain(ctx, chainID)
 	if err != nil {
-		return bluesky.RespImageUpload{}, fmt.Errorf("failed to get sprite for pokemon: %w", err)
+		return "", err
 	}
 
-	if len(resp.Body()) > 1000000 {
-		return bluesky.RespImageUpload{}, fmt.Errorf("image too large")
+	return formatEvolutionChain(chain, speciesNames(ctx, api, chain)), nil
+}
+
+func formatSprite(ctx context.Context, api PokeAPI, url string) ([]byte, error) {
+	sprite, err := api.GetSprite(ctx, url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sprite for pokemon: %w", err)
 	}
 
-	uploadedImage, uploadedImageErr := bluesky.UploadImage(context.Background(), resp.Body())
-	if uploadedImageErr != nil {
-		return bluesky.RespImageUpload{}, fmt.Errorf("failed to upload sprite: %w", uploadedImageErr)
+	if len(sprite) > 1000000 {
+		return nil, fmt.Errorf("image too large")
 	}
 
-	return uploadedImage, nil
+	return sprite, nil
+}
+
+// PublishOptions changes what Publish does. The zero value publishes the
+// day's pick.
+type PublishOptions struct {
+	// Override publishes a specific pokemon instead of the day's pick.
+	Override *Override
+
+	// DryRun renders the post into DryRunDir instead of publishing it.
+	DryRun    bool
+	DryRunDir string
 }
 
-var bucket = gcp.Bucket{}
+func Publish(opts PublishOptions) (string, error) {
+	ctx := context.Background()
+
+	if opts.DryRun {
+		rendered, err := DryRun(opts)
+		if err != nil {
+			return "", err
+		}
 
-func Publish() (string, error) {
+		if err := rendered.Write(opts.DryRunDir); err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("dry run of pokemon #%d written to %s", rendered.PokemonID, opts.DryRunDir), nil
+	}
+
+	bucket, err := newBucketFromEnv(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	p, err := newPublisher(ctx, bucket, newBucketState(bucket))
+	if err != nil {
+		return "", err
+	}
+
+	return p.publish(ctx, time.Now(), opts, sendPost)
+}
+
+// publish posts the pokemon planned for opts on the day of now through send,
+// then records it in the leases, the history and the selector state.
+func (p *publisher) publish(ctx context.Context, now time.Time, opts PublishOptions, send func(context.Context, draft) (sentPost, error)) (string, error) {
 	logger := zerolog.New(os.Stdout)
 
-	var pokemonToPublish int
-	var publishErr error
-	for {
-		rand.Seed(uint64(time.Now().Unix()))
-		pokemonToPublish = rand.Intn(1025) + 1
-		alreadyPublished, readErr := readHistory(context.Background(), pokemonToPublish)
-		if readErr != nil {
-			logger.Err(readErr).Msgf("failed to check if pokemon #%d has been published already; may be double-published", pokemonToPublish)
+	planned, err := p.planFor(ctx, now, opts)
+	if err != nil {
+		return "", err
+	}
+
+	// claim the slot before posting so overlapping invocations and retries
+	// cannot publish twice.
+	claimed, err := newClaims(p.bucket)
+	if err != nil {
+		return "", err
+	}
+
+	// releasing the claims lets a retry publish once a post fails before
+	// anything was posted.
+	fail := func(err error) (string, error) {
+		if releaseErr := claimed.release(ctx); releaseErr != nil {
+			logger.Err(releaseErr).Msg("failed to release claims")
+		}
+		return "", err
+	}
+
+	if opts.Override == nil {
+		if err := claimed.claimDay(ctx, now, planned.Date); err != nil {
+			return "", err
 		}
-		if !alreadyPublished {
-			publishErr = createPost(pokemonToPublish)
+	}
+	// the pokemon is claimed on scheduled runs too, so a manual publish of
+	// the same pokemon cannot run alongside them.
+	republish := opts.Override == nil || opts.Override.Force
+	if err := claimed.claimPokemon(ctx, now, planned, republish); err != nil {
+		return fail(err)
+	}
 
-			if publishErr != nil {
-				publishErr = fmt.Errorf("failed to publish pokemon #%d: %w", pokemonToPublish, publishErr)
-				break
-			}
+	rendered, err := renderPost(ctx, p.api, planned.PokemonID, postOptions{Shiny: planned.Shiny, Theme: planned.Selection.Theme})
+	if err != nil {
+		return fail(fmt.Errorf("failed to publish pokemon #%d: %w", planned.PokemonID, err))
+	}
+	planned.Shiny = rendered.Shiny
+
+	sent, err := send(ctx, rendered)
+	if err != nil {
+		if sent.Post.URI == "" {
+			return fail(fmt.Errorf("failed to publish pokemon #%d: %w", planned.PokemonID, err))
+		}
+		logger.Err(err).Msgf("published pokemon #%d without all of its replies", planned.PokemonID)
+	}
 
-			logger.Info().Msg("successfully created a post on Bluesky")
+	logger.Info().Msgf("successfully created post %s on Bluesky", sent.Post.URI)
 
-			if err := updateHistory(context.Background(), pokemonToPublish); err != nil {
-				logger.Err(err).Msg("failed to save the published pokemon to the history; this pokemon may be published again")
-			}
+	if err := claimed.markPublished(ctx, time.Now(), sent.Post.URI); err != nil {
+		logger.Err(err).Msg("failed to mark the claims published")
+	}
 
-			return fmt.Sprintf("successfully published pokemon #%d", pokemonToPublish), nil
+	if err := updateHistory(ctx, p.bucket, newHistoryEntry(time.Now(), planned, rendered, sent)); err != nil {
+		logger.Err(err).Msg("failed to save the published pokemon to the history; this pokemon may be published again")
+	}
+
+	if opts.Override == nil {
+		if err := p.selector.Commit(ctx, planned.Selection); err != nil {
+			logger.Err(err).Msg("failed to save the selector state; this pokemon may be published again")
 		}
 	}
-	return "", publishErr
-}
 
-func alreadyPublished(pokemonNum int, previouslyPublished map[int]bool) bool {
-	return previouslyPublished[pokemonNum]
+	result := fmt.Sprintf("successfully published pokemon #%d", planned.PokemonID)
+	if planned.Shiny {
+		result = fmt.Sprintf("successfully published shiny pokemon #%d", planned.PokemonID)
+	}
+	if planned.Selection.Progress != "" {
+		result += fmt.Sprintf(" (%s)", planned.Selection.Progress)
+	}
+
+	return result, nil
 }
 
-func updateHistory(ctx context.Context, num int) error {
-	return bucket.CreateFile(ctx, fmt.Sprintf("%d", num))
+// newPokeAPI returns the offline client when a data dump is configured and
+// the HTTP client otherwise.
+func newPokeAPI(cfg ClientConfig, bucket cloud.FileBucket) (PokeAPI, error) {
+	if cfg.DumpDir != "" {
+		return loadDumpOnce(cfg.DumpDir, cfg.SpriteDir)
+	}
+
+	cacheStore, err := newCacheStore(cfg, bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up PokeAPI cache: %w", err)
+	}
+
+	return NewHTTPClient(cfg, cacheStore), nil
 }
 
-func readHistory(ctx context.Context, pokemonNumber int) (bool, error) {
-	return bucket.FileExists(ctx, fmt.Sprintf("%d", pokemonNumber))
+// newCacheStore returns the cache selected by cfg.Cache, or nil when caching
+// is disabled.
+func newCacheStore(cfg ClientConfig, bucket cloud.FileBucket) (cache.Store, error) {
+	switch cfg.Cache {
+	case CacheDir:
+		return cache.NewDirStore(cfg.CacheDir)
+	case CacheBucket:
+		return cache.NewBucketStore(bucket, "pokeapi-cache/"), nil
+	default:
+		return nil, nil
+	}
 }
 
-func createStatsChart(stats map[string]float64, name string) (bluesky.RespImageUpload, error) {
+func createStatsChart(stats map[string]float64, name string) ([]byte, error) {
 	// a map does not necessarily have the same order of keys every time
 	// this causes the stats to be in a random order during each run and causes the charts to
 	// not be standardized.
@@ -260,17 +475,15 @@ func createStatsChart(stats map[string]float64, name string) (bluesky.RespImageU
 			}),
 	)
 	if err != nil {
-		return bluesky.RespImageUpload{}, fmt.Errorf("failed to create stats chart: %w", err)
+		return nil, fmt.Errorf("failed to create stats chart: %w", err)
 	}
 	buf, err := chart.Bytes()
-	if len(buf) > 1000000 {
-		return bluesky.RespImageUpload{}, fmt.Errorf("image too large")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render stats chart: %w", err)
 	}
-
-	uploadedImage, uploadedImageErr := bluesky.UploadImage(context.Background(), buf)
-	if uploadedImageErr != nil {
-		return bluesky.RespImageUpload{}, uploadedImageErr
+	if len(buf) > 1000000 {
+		return nil, fmt.Errorf("image too large")
 	}
 
-	return uploadedImage, nil
+	return buf, nil
 }
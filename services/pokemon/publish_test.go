@@ -0,0 +1,173 @@
+package pokemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/bluesky"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud/memory"
+)
+
+// newTestPublisher returns a publisher that publishes species 1 to 3 in dex
+// order from bucket.
+func newTestPublisher(bucket *memory.Bucket) (*publisher, *fakeAPI) {
+	api := newFakeAPI()
+	for id := 1; id <= 3; id++ {
+		name := fmt.Sprintf("species-%d", id)
+		artwork := fmt.Sprintf("https://sprites.test/%d.png", id)
+
+		stats := []Stats{}
+		for _, stat := range []string{"hp", "attack", "defense", "special-attack", "special-defense", "speed"} {
+			stats = append(stats, Stats{BaseStat: 50, Stat: Stat{Name: stat}})
+		}
+
+		api.pokemon[id] = RespPokemon{
+			ID:      id,
+			Name:    name,
+			Species: Species{Name: name, URL: fmt.Sprintf("https://pokeapi.co/api/v2/pokemon-species/%d/", id)},
+			Sprites: Sprites{Other: Other{OfficialArtwork: OfficialArtwork{FrontDefault: artwork}}},
+			Stats:   stats,
+			Types:   []Types{{Slot: 1, Type: Type{Name: "normal"}}},
+		}
+		api.species[id] = RespPokemonSpecies{
+			Name:              name,
+			FlavorTextEntries: []FlavorTextEntries{{FlavorText: "A test pokemon.", Language: Language{Name: "en"}}},
+		}
+		api.sprites[artwork] = []byte("png")
+	}
+
+	state := newBucketState(bucket)
+	return &publisher{
+		bucket:   bucket,
+		api:      api,
+		state:    state,
+		selector: &sequentialSelector{state: state},
+		eligible: []int{1, 2, 3},
+		shiny:    ShinyConfig{Odds: defaultShinyOdds},
+		schedule: ScheduleConfig{Location: time.UTC},
+	}, api
+}
+
+// fakeSend records the posts sent and answers with a post URI per post.
+type fakeSend struct {
+	sent []draft
+}
+
+func (f *fakeSend) send(ctx context.Context, rendered draft) (sentPost, error) {
+	f.sent = append(f.sent, rendered)
+	return sentPost{Post: bluesky.RespCreatePost{URI: fmt.Sprintf("at://test/post/%d", len(f.sent))}}, nil
+}
+
+func TestPublish(t *testing.T) {
+	ctx := context.Background()
+	bucket := memory.NewBucket()
+	p, _ := newTestPublisher(bucket)
+	send := &fakeSend{}
+	now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+
+	res, err := p.publish(ctx, now, PublishOptions{}, send.send)
+	if err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	if res != "successfully published pokemon #1 (1/3)" {
+		t.Errorf("publish = %q, want the publish of pokemon #1", res)
+	}
+	if len(send.sent) != 1 || send.sent[0].Name != "Species 1" {
+		t.Fatalf("sent %d posts, want one post about Species 1", len(send.sent))
+	}
+
+	entry, err := readHistoryEntry(ctx, bucket, 1)
+	if err != nil {
+		t.Fatalf("readHistoryEntry failed: %v", err)
+	}
+	if entry.SpeciesID != 1 || entry.PostURI != "at://test/post/1" {
+		t.Errorf("history entry = %+v, want species #1 posted in at://test/post/1", entry)
+	}
+
+	var position sequentialState
+	if _, err := p.state.readState(ctx, sequentialObject, &position); err != nil {
+		t.Fatalf("readState failed: %v", err)
+	}
+	if position.Next != 2 {
+		t.Errorf("next species = %d, want 2", position.Next)
+	}
+
+	// a retry on the same day finds the day published
+	if _, err := p.publish(ctx, now.Add(time.Hour), PublishOptions{}, send.send); err == nil {
+		t.Errorf("second publish on the same day succeeded, want an error")
+	}
+	if len(send.sent) != 1 {
+		t.Errorf("sent %d posts, want 1", len(send.sent))
+	}
+}
+
+// TestPublishOverrideDuringScheduledRun starts a scheduled run while an
+// override of the same pokemon is posting.
+func TestPublishOverrideDuringScheduledRun(t *testing.T) {
+	ctx := context.Background()
+	bucket := memory.NewBucket()
+	p, _ := newTestPublisher(bucket)
+	now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+
+	var scheduledErr error
+	sent := 0
+	send := func(ctx context.Context, rendered draft) (sentPost, error) {
+		sent++
+		if sent == 1 {
+			_, scheduledErr = p.publish(ctx, now, PublishOptions{}, func(context.Context, draft) (sentPost, error) {
+				t.Errorf("the scheduled run posted while the override was posting")
+				return sentPost{}, nil
+			})
+		}
+		return sentPost{Post: bluesky.RespCreatePost{URI: "at://test/post/1"}}, nil
+	}
+
+	if _, err := p.publish(ctx, now, PublishOptions{Override: &Override{ID: 1}}, send); err != nil {
+		t.Fatalf("override failed: %v", err)
+	}
+	if scheduledErr == nil || !strings.Contains(scheduledErr.Error(), "pokemon #1 is being published") {
+		t.Fatalf("scheduled run error = %v, want pokemon #1 to be held by the override", scheduledErr)
+	}
+
+	// the scheduled run let go of the day, so it can be retried
+	exists, err := bucket.FileExists(ctx, dayLeaseObject("2026-10-18"))
+	if err != nil {
+		t.Fatalf("FileExists failed: %v", err)
+	}
+	if exists {
+		t.Errorf("the day lease was kept after the scheduled run failed")
+	}
+}
+
+// TestPublishRepublishes checks that a pokemon published before can be
+// published again by a scheduled run or a forced override, but not by an
+// override without force.
+func TestPublishRepublishes(t *testing.T) {
+	ctx := context.Background()
+	bucket := memory.NewBucket()
+	p, _ := newTestPublisher(bucket)
+	send := &fakeSend{}
+	now := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+
+	if _, err := p.publish(ctx, now, PublishOptions{Override: &Override{ID: 1}}, send.send); err != nil {
+		t.Fatalf("override failed: %v", err)
+	}
+	if _, err := p.publish(ctx, now, PublishOptions{Override: &Override{ID: 1}}, send.send); err == nil {
+		t.Errorf("second override without force succeeded, want an error")
+	}
+
+	// the sequential selector picks pokemon #1 regardless of the history
+	if _, err := p.publish(ctx, now, PublishOptions{}, send.send); err != nil {
+		t.Errorf("scheduled run failed: %v", err)
+	}
+	if _, err := p.publish(ctx, now, PublishOptions{Override: &Override{ID: 1, Force: true}}, send.send); err != nil {
+		t.Errorf("forced override failed: %v", err)
+	}
+
+	if len(send.sent) != 3 {
+		t.Errorf("sent %d posts, want 3", len(send.sent))
+	}
+}
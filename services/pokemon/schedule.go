@@ -0,0 +1,243 @@
+package pokemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+	"golang.org/x/exp/rand"
+)
+
+// ScheduleConfig makes each day's pick a reproducible function of the date,
+// a secret salt and the history, so re-running a failed job on the same day
+// picks the same pokemon and upcoming days can be previewed.
+type ScheduleConfig struct {
+	// Salt keeps the schedule from being predicted by anyone who knows the
+	// algorithm.
+	Salt     string
+	Location *time.Location
+}
+
+// ScheduleConfigFromEnv reads SELECTION_SALT and SCHEDULE_TIMEZONE, the IANA
+// time zone whose calendar days are used (default UTC).
+func ScheduleConfigFromEnv() (ScheduleConfig, error) {
+	cfg := ScheduleConfig{
+		Salt:     os.Getenv("SELECTION_SALT"),
+		Location: time.UTC,
+	}
+
+	if timezone := os.Getenv("SCHEDULE_TIMEZONE"); timezone != "" {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return cfg, fmt.Errorf("SCHEDULE_TIMEZONE is not a valid time zone: %w", err)
+		}
+		cfg.Location = location
+	}
+
+	return cfg, nil
+}
+
+// date returns the calendar day of t in the schedule's time zone.
+func (cfg ScheduleConfig) date(t time.Time) string {
+	return t.In(cfg.Location).Format(time.DateOnly)
+}
+
+// rand returns a generator seeded from the salt, the day and purpose. Each
+// decision gets its own purpose so that changing how one of them consumes
+// randomness does not change the others.
+func (cfg ScheduleConfig) rand(date string, purpose string) *rand.Rand {
+	sum := sha256.Sum256([]byte(cfg.Salt + "\x00" + date + "\x00" + purpose))
+	return rand.New(rand.NewSource(binary.BigEndian.Uint64(sum[:8])))
+}
+
+// publisher holds the configuration and clients needed to decide what is
+// published on a day.
+type publisher struct {
+	bucket       cloud.FileBucket
+	api          PokeAPI
+	state        selectionState
+	selector     Selector
+	eligible     []int
+	filters      listFilters
+	shiny        ShinyConfig
+	includeForms bool
+	schedule     ScheduleConfig
+}
+
+// newPublisher loads the configuration from the environment and sets up
+// selection on top of state. bucket is used for anything else kept in the
+// bucket, such as the PokeAPI cache.
+func newPublisher(ctx context.Context, bucket cloud.FileBucket, state selectionState) (*publisher, error) {
+	clientConfig, configErr := ClientConfigFromEnv()
+	if configErr != nil {
+		return nil, fmt.Errorf("failed to load PokeAPI client config: %w", configErr)
+	}
+	api, apiErr := newPokeAPI(clientConfig, bucket)
+	if apiErr != nil {
+		return nil, fmt.Errorf("failed to set up PokeAPI client: %w", apiErr)
+	}
+
+	shinyConfig, shinyConfigErr := ShinyConfigFromEnv()
+	if shinyConfigErr != nil {
+		return nil, fmt.Errorf("failed to load shiny config: %w", shinyConfigErr)
+	}
+
+	includeForms, includeFormsErr := includeFormsFromEnv()
+	if includeFormsErr != nil {
+		return nil, includeFormsErr
+	}
+
+	dexConfig, dexConfigErr := DexConfigFromEnv()
+	if dexConfigErr != nil {
+		return nil, fmt.Errorf("failed to load dex config: %w", dexConfigErr)
+	}
+
+	selectorConfig, selectorConfigErr := SelectorConfigFromEnv()
+	if selectorConfigErr != nil {
+		return nil, fmt.Errorf("failed to load selector config: %w", selectorConfigErr)
+	}
+
+	scheduleConfig, scheduleConfigErr := ScheduleConfigFromEnv()
+	if scheduleConfigErr != nil {
+		return nil, fmt.Errorf("failed to load schedule config: %w", scheduleConfigErr)
+	}
+
+	eligible, eligibleErr := eligibleSpecies(ctx, api, dexConfig)
+	if eligibleErr != nil {
+		return nil, eligibleErr
+	}
+
+	filters, filtersErr := loadListFilters(ctx, api, state)
+	if filtersErr != nil {
+		return nil, filtersErr
+	}
+
+	eligible, eligibleErr = filters.apply(ctx, api, state, eligible)
+	if eligibleErr != nil {
+		return nil, eligibleErr
+	}
+
+	selector, selectorErr := newSelector(ctx, selectorConfig, api, state)
+	if selectorErr != nil {
+		return nil, fmt.Errorf("failed to set up %s selector: %w", selectorConfig.Strategy, selectorErr)
+	}
+
+	rules, rulesErr := loadRulesFromEnv()
+	if rulesErr != nil {
+		return nil, rulesErr
+	}
+	if len(rules) > 0 {
+		selector = &themedSelector{inner: selector, rules: rules, api: api, state: state, filters: filters}
+	}
+
+	return &publisher{
+		bucket:       bucket,
+		api:          api,
+		state:        state,
+		selector:     selector,
+		eligible:     eligible,
+		filters:      filters,
+		shiny:        shinyConfig,
+		includeForms: includeForms,
+		schedule:     scheduleConfig,
+	}, nil
+}
+
+// plan is what gets published on a day.
+type plan struct {
+	Date      string
+	Selection Selection
+	PokemonID int
+	Shiny     bool
+}
+
+// plan decides what to publish on the day of now. Errors from checking which
+// varieties were published are returned alongside a usable plan.
+func (p *publisher) plan(ctx context.Context, now time.Time) (plan, error) {
+	date := p.schedule.date(now)
+
+	selection, err := p.selector.Next(ctx, now.In(p.schedule.Location), p.eligible, p.schedule.rand(date, "species"))
+	if err != nil {
+		return plan{}, fmt.Errorf("failed to select the pokemon for %s: %w", date, err)
+	}
+
+	pokemonID, varietyErr := chooseVariety(ctx, p.api, p.state, p.filters, selection.SpeciesID, p.includeForms, p.schedule.rand(date, "variety"))
+	if varietyErr != nil {
+		varietyErr = fmt.Errorf("failed to check which varieties of species #%d have been published already; may be double-published: %w", selection.SpeciesID, varietyErr)
+	}
+
+	return plan{
+		Date:      date,
+		Selection: selection,
+		PokemonID: pokemonID,
+		Shiny:     p.shiny.rollShiny(date, p.schedule.rand(date, "shiny")),
+	}, varietyErr
+}
+
+// PreviewEntry is a pokemon scheduled for an upcoming day.
+type PreviewEntry struct {
+	Date      string
+	PokemonID int
+	Name      string
+	Shiny     bool
+	Progress  string
+	Theme     string
+}
+
+// Preview lists what would be published over the next days, starting today,
+// assuming every publish succeeds. Nothing is written to the bucket.
+func Preview(days int) ([]PreviewEntry, error) {
+	ctx := context.Background()
+	bucket, err := newBucketFromEnv(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state := newPreviewState(bucket)
+	p, err := newPublisher(ctx, bucket, state)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []PreviewEntry{}
+	now := time.Now()
+
+	for day := 0; day < days; day++ {
+		planned, err := p.plan(ctx, now.AddDate(0, 0, day))
+		if err != nil && planned.PokemonID == 0 {
+			return entries, err
+		}
+
+		name := fmt.Sprintf("#%d", planned.PokemonID)
+		if pokemon, err := p.api.GetPokemon(ctx, planned.PokemonID); err == nil {
+			name = lookupDisplayName(ctx, p.api, pokemon)
+			// the default artwork is posted instead of a missing shiny one
+			if pokemon.Sprites.Other.OfficialArtwork.FrontShiny == "" {
+				planned.Shiny = false
+			}
+		}
+
+		entry := PreviewEntry{
+			Date:      planned.Date,
+			PokemonID: planned.PokemonID,
+			Name:      name,
+			Shiny:     planned.Shiny,
+			Progress:  planned.Selection.Progress,
+		}
+		if planned.Selection.Theme != nil {
+			entry.Theme = planned.Selection.Theme.Name
+		}
+		entries = append(entries, entry)
+
+		state.markPublished(newHistoryEntry(now.AddDate(0, 0, day), planned, draft{Name: name}, sentPost{}))
+		if err := p.selector.Commit(ctx, planned.Selection); err != nil {
+			return entries, err
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,457 @@
+package pokemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/rand"
+)
+
+// supported values of SELECTOR
+const (
+	SelectorDeck       = "deck"
+	SelectorRandom     = "random"
+	SelectorSequential = "sequential"
+	SelectorWeighted   = "weighted"
+	SelectorList       = "list"
+)
+
+const (
+	sequentialObject = "sequential.json"
+	listObject       = "list.json"
+	roundObject      = "round.json"
+)
+
+// Selection is the species chosen for the next publish.
+type Selection struct {
+	SpeciesID int
+
+	// Progress describes where the selector is, e.g. "season 2, 17/1025".
+	// It is empty for selectors without a notion of progress.
+	Progress string
+
+	// Season is the deck season the species was drawn in, or 0 for
+	// selectors without seasons.
+	Season int
+
+	// Theme is the calendar rule the species was picked for, if any.
+	Theme *Theme
+}
+
+// Selector chooses which species to publish on day out of the eligible ones.
+// Any randomness comes from rng so that a day's pick is reproducible. Commit
+// is called once the selection has been published so stateful selectors can
+// move on; a selection that fails to publish is not committed and is offered
+// again on the next run.
+type Selector interface {
+	Next(ctx context.Context, day time.Time, eligible []int, rng *rand.Rand) (Selection, error)
+	Commit(ctx context.Context, selection Selection) error
+}
+
+// SelectorConfig chooses the Selector implementation.
+type SelectorConfig struct {
+	Strategy string
+
+	// File is the weights file of the weighted selector or the list of dex
+	// numbers of the list selector.
+	File string
+}
+
+// SelectorConfigFromEnv reads SELECTOR and SELECTOR_FILE.
+func SelectorConfigFromEnv() (SelectorConfig, error) {
+	cfg := SelectorConfig{
+		Strategy: os.Getenv("SELECTOR"),
+		File:     os.Getenv("SELECTOR_FILE"),
+	}
+
+	switch cfg.Strategy {
+	case "":
+		cfg.Strategy = SelectorDeck
+	case SelectorDeck, SelectorRandom, SelectorSequential:
+	case SelectorWeighted, SelectorList:
+		if cfg.File == "" {
+			return cfg, fmt.Errorf("SELECTOR_FILE is required for the %s selector", cfg.Strategy)
+		}
+	default:
+		return cfg, fmt.Errorf("SELECTOR must be one of %s, %s, %s, %s or %s, got %q",
+			SelectorDeck, SelectorRandom, SelectorSequential, SelectorWeighted, SelectorList, cfg.Strategy)
+	}
+
+	return cfg, nil
+}
+
+// newSelector builds the selector chosen by cfg on top of state.
+func newSelector(ctx context.Context, cfg SelectorConfig, api PokeAPI, state selectionState) (Selector, error) {
+	switch cfg.Strategy {
+	case SelectorRandom:
+		return &randomSelector{roundPool: roundPool{state: state}}, nil
+	case SelectorSequential:
+		return &sequentialSelector{state: state}, nil
+	case SelectorWeighted:
+		weights, err := loadWeights(ctx, api, cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		return &weightedSelector{roundPool: roundPool{state: state}, weights: weights}, nil
+	case SelectorList:
+		ids, err := loadList(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		return &listSelector{state: state, ids: ids}, nil
+	default:
+		return &deckSelector{state: state}, nil
+	}
+}
+
+// round tracks the species published since the random and weighted
+// selectors last ran out of unpublished species. The first round is the
+// publish history itself, so round.json only exists from the second round
+// on.
+type round struct {
+	Round     int   `json:"round"`
+	Published []int `json:"published"`
+}
+
+// roundPool is the pool of the random and weighted selectors: the species
+// not published yet in the current round. Once every species has been
+// published, a new round starts with all of them, so the selectors keep
+// going instead of failing forever.
+type roundPool struct {
+	state selectionState
+	round round
+
+	// total and left are the size of the round and the species still left
+	// in it, for the progress of the selection.
+	total int
+	left  int
+}
+
+// available returns the species of ids not yet published in the current
+// round, starting the next round if there are none. The new round is only
+// saved once a selection from it is committed.
+func (p *roundPool) available(ctx context.Context, ids []int) ([]int, error) {
+	p.round = round{}
+	found, err := p.state.readState(ctx, roundObject, &p.round)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read round: %w", err)
+	}
+
+	var unpublished []int
+	if found {
+		published := make(map[int]bool, len(p.round.Published))
+		for _, id := range p.round.Published {
+			published[id] = true
+		}
+
+		unpublished = []int{}
+		for _, id := range ids {
+			if !published[id] {
+				unpublished = append(unpublished, id)
+			}
+		}
+	} else if unpublished, err = unpublishedSpecies(ctx, p.state, ids); err != nil {
+		return nil, err
+	}
+
+	if len(unpublished) == 0 {
+		next := 2
+		if found {
+			next = p.round.Round + 1
+		}
+		p.round = round{Round: next}
+		unpublished = append([]int{}, ids...)
+	}
+
+	p.total = len(ids)
+	p.left = len(unpublished)
+	return unpublished, nil
+}
+
+// take returns id as picked from the current round.
+func (p *roundPool) take(id int) Selection {
+	number := p.round.Round
+	if number == 0 {
+		number = 1
+	}
+
+	return Selection{SpeciesID: id, Progress: fmt.Sprintf("round %d, %d/%d", number, p.total-p.left+1, p.total)}
+}
+
+// Commit records the selection in the current round. The first round needs
+// nothing saved, since the publish history already has it.
+func (p *roundPool) Commit(ctx context.Context, selection Selection) error {
+	if p.round.Round == 0 {
+		return nil
+	}
+
+	p.round.Published = append(p.round.Published, selection.SpeciesID)
+	if err := p.state.writeState(ctx, roundObject, p.round); err != nil {
+		return fmt.Errorf("failed to save round: %w", err)
+	}
+
+	return nil
+}
+
+// randomSelector picks uniformly among the species that have not been
+// published yet in the current round.
+type randomSelector struct {
+	roundPool
+}
+
+func (s *randomSelector) Next(ctx context.Context, day time.Time, eligible []int, rng *rand.Rand) (Selection, error) {
+	unpublished, err := s.available(ctx, eligible)
+	if err != nil {
+		return Selection{}, err
+	}
+
+	if len(unpublished) == 0 {
+		return Selection{}, fmt.Errorf("no species are eligible")
+	}
+
+	return s.take(unpublished[rng.Intn(len(unpublished))]), nil
+}
+
+// sequentialSelector walks through the eligible species in national dex
+// order, wrapping around after the last one.
+type sequentialSelector struct {
+	state selectionState
+}
+
+type sequentialState struct {
+	Next int `json:"next"`
+}
+
+func (s *sequentialSelector) Next(ctx context.Context, day time.Time, eligible []int, rng *rand.Rand) (Selection, error) {
+	if len(eligible) == 0 {
+		return Selection{}, fmt.Errorf("no species are eligible")
+	}
+
+	var state sequentialState
+	if _, err := s.state.readState(ctx, sequentialObject, &state); err != nil {
+		return Selection{}, fmt.Errorf("failed to read dex order position: %w", err)
+	}
+
+	// eligible is sorted, so the first species at or after the cursor is
+	// next.
+	for i, id := range eligible {
+		if id >= state.Next {
+			return Selection{SpeciesID: id, Progress: fmt.Sprintf("%d/%d", i+1, len(eligible))}, nil
+		}
+	}
+
+	return Selection{SpeciesID: eligible[0], Progress: fmt.Sprintf("1/%d", len(eligible))}, nil
+}
+
+func (s *sequentialSelector) Commit(ctx context.Context, selection Selection) error {
+	if err := s.state.writeState(ctx, sequentialObject, sequentialState{Next: selection.SpeciesID + 1}); err != nil {
+		return fmt.Errorf("failed to save dex order position: %w", err)
+	}
+
+	return nil
+}
+
+// weightsFile is the format of the weighted selector's SELECTOR_FILE. Weights
+// default to 1; a species' weight is its own weight multiplied by the weight
+// of its generation.
+type weightsFile struct {
+	Species     map[string]float64 `json:"species"`
+	Generations map[string]float64 `json:"generations"`
+}
+
+// loadWeights reads the weights file and resolves generation weights to the
+// species of each generation.
+func loadWeights(ctx context.Context, api PokeAPI, path string) (map[int]float64, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read weights file: %w", err)
+	}
+
+	var file weightsFile
+	if err := json.Unmarshal(content, &file); err != nil {
+		return nil, fmt.Errorf("weights file is not a valid JSON: %w", err)
+	}
+
+	weights := make(map[int]float64)
+	weightOf := func(id int) float64 {
+		if weight, ok := weights[id]; ok {
+			return weight
+		}
+		return 1
+	}
+
+	for key, weight := range file.Species {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("weights file has an invalid species %q: %w", key, err)
+		}
+		weights[id] = weightOf(id) * weight
+	}
+
+	for key, weight := range file.Generations {
+		generationID, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("weights file has an invalid generation %q: %w", key, err)
+		}
+
+		generation, err := api.GetGeneration(ctx, generationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get species of generation %d: %w", generationID, err)
+		}
+
+		for _, species := range generation.PokemonSpecies {
+			id, err := idFromURL(species.URL)
+			if err != nil {
+				return nil, err
+			}
+			weights[id] = weightOf(id) * weight
+		}
+	}
+
+	for id, weight := range weights {
+		if weight < 0 {
+			return nil, fmt.Errorf("weights file gives species %d a negative weight", id)
+		}
+	}
+
+	return weights, nil
+}
+
+// weightedSelector picks among the species that have not been published yet
+// in the current round with a probability proportional to their weight.
+// Species with a weight of 0 are never picked, so they are left out of the
+// rounds.
+type weightedSelector struct {
+	roundPool
+	weights map[int]float64
+}
+
+func (s *weightedSelector) Next(ctx context.Context, day time.Time, eligible []int, rng *rand.Rand) (Selection, error) {
+	unpublished, err := s.available(ctx, eligible)
+	if err != nil {
+		return Selection{}, err
+	}
+
+	total := float64(0)
+	for _, id := range unpublished {
+		total += s.weight(id)
+	}
+
+	target := rng.Float64() * total
+	for _, id := range unpublished {
+		target -= s.weight(id)
+		if target < 0 {
+			return s.take(id), nil
+		}
+	}
+
+	// floating point rounding can leave target at exactly zero
+	return s.take(unpublished[len(unpublished)-1]), nil
+}
+
+// available leaves species without a positive weight out of the rounds.
+func (s *weightedSelector) available(ctx context.Context, eligible []int) ([]int, error) {
+	weighted := []int{}
+	for _, id := range eligible {
+		if s.weight(id) > 0 {
+			weighted = append(weighted, id)
+		}
+	}
+
+	if len(weighted) == 0 {
+		return nil, fmt.Errorf("no eligible species has a positive weight")
+	}
+
+	return s.roundPool.available(ctx, weighted)
+}
+
+func (s *weightedSelector) weight(id int) float64 {
+	if weight, ok := s.weights[id]; ok {
+		return weight
+	}
+	return 1
+}
+
+// loadList reads one national dex number per line. Blank lines and lines
+// starting with # are ignored.
+func loadList(path string) ([]int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open list file: %w", err)
+	}
+	defer f.Close()
+
+	ids := []int{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		id, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d of list file is not a dex number: %w", line, err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read list file: %w", err)
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("list file %s is empty", path)
+	}
+
+	return ids, nil
+}
+
+// listSelector publishes the species of a fixed list in order, wrapping
+// around after the last one. Listed species that are not eligible are
+// skipped.
+type listSelector struct {
+	state    selectionState
+	ids      []int
+	position int
+}
+
+type listState struct {
+	Position int `json:"position"`
+}
+
+func (s *listSelector) Next(ctx context.Context, day time.Time, eligible []int, rng *rand.Rand) (Selection, error) {
+	var state listState
+	if _, err := s.state.readState(ctx, listObject, &state); err != nil {
+		return Selection{}, fmt.Errorf("failed to read list position: %w", err)
+	}
+
+	eligibleSet := make(map[int]bool, len(eligible))
+	for _, id := range eligible {
+		eligibleSet[id] = true
+	}
+
+	for i := 0; i < len(s.ids); i++ {
+		position := (state.Position + i) % len(s.ids)
+		if eligibleSet[s.ids[position]] {
+			s.position = position
+			return Selection{SpeciesID: s.ids[position], Progress: fmt.Sprintf("%d/%d", position+1, len(s.ids))}, nil
+		}
+	}
+
+	return Selection{}, fmt.Errorf("none of the species in the list are eligible")
+}
+
+func (s *listSelector) Commit(ctx context.Context, selection Selection) error {
+	if err := s.state.writeState(ctx, listObject, listState{Position: (s.position + 1) % len(s.ids)}); err != nil {
+		return fmt.Errorf("failed to save list position: %w", err)
+	}
+
+	return nil
+}
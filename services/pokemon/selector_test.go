@@ -0,0 +1,225 @@
+package pokemon
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud/memory"
+	"golang.org/x/exp/rand"
+)
+
+// publishedVariety is a history entry as Publish writes it.
+type publishedVariety struct {
+	pokemonID int
+	speciesID int
+}
+
+func newTestBucket(t *testing.T, history []publishedVariety, objects map[string]any) *memory.Bucket {
+	t.Helper()
+
+	ctx := context.Background()
+	bucket := memory.NewBucket()
+	publishedAt := time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC)
+
+	for _, variety := range history {
+		entry := historyEntry{PublishedAt: &publishedAt, PokemonID: variety.pokemonID, SpeciesID: variety.speciesID}
+		if err := updateHistory(ctx, bucket, entry); err != nil {
+			t.Fatalf("updateHistory failed: %v", err)
+		}
+	}
+
+	for object, v := range objects {
+		content, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to encode %s: %v", object, err)
+		}
+		if err := bucket.CreateFile(ctx, object, content); err != nil {
+			t.Fatalf("CreateFile(%q) failed: %v", object, err)
+		}
+	}
+
+	return bucket
+}
+
+func TestRoundPool(t *testing.T) {
+	tests := []struct {
+		name         string
+		history      []publishedVariety
+		round        *round
+		wantLeft     []int
+		wantProgress string
+		// wantRound is round.json after committing the first species left,
+		// or nil if nothing is saved.
+		wantRound *round
+	}{
+		{
+			name:         "nothing published",
+			wantLeft:     []int{25, 26, 27},
+			wantProgress: "round 1, 1/3",
+		},
+		{
+			name:         "default variety published",
+			history:      []publishedVariety{{pokemonID: 25, speciesID: 25}},
+			wantLeft:     []int{26, 27},
+			wantProgress: "round 1, 2/3",
+		},
+		{
+			name:         "form published",
+			history:      []publishedVariety{{pokemonID: 10100, speciesID: 26}},
+			wantLeft:     []int{25, 27},
+			wantProgress: "round 1, 2/3",
+		},
+		{
+			name: "every species published",
+			history: []publishedVariety{
+				{pokemonID: 25, speciesID: 25},
+				{pokemonID: 10100, speciesID: 26},
+				{pokemonID: 27, speciesID: 27},
+			},
+			wantLeft:     []int{25, 26, 27},
+			wantProgress: "round 2, 1/3",
+			wantRound:    &round{Round: 2, Published: []int{25}},
+		},
+		{
+			name:         "second round under way",
+			history:      []publishedVariety{{pokemonID: 25, speciesID: 25}, {pokemonID: 26, speciesID: 26}, {pokemonID: 27, speciesID: 27}},
+			round:        &round{Round: 2, Published: []int{26}},
+			wantLeft:     []int{25, 27},
+			wantProgress: "round 2, 2/3",
+			wantRound:    &round{Round: 2, Published: []int{26, 25}},
+		},
+		{
+			name:         "second round done",
+			round:        &round{Round: 2, Published: []int{25, 26, 27}},
+			wantLeft:     []int{25, 26, 27},
+			wantProgress: "round 3, 1/3",
+			wantRound:    &round{Round: 3, Published: []int{25}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			objects := map[string]any{}
+			if tt.round != nil {
+				objects[roundObject] = tt.round
+			}
+			bucket := newTestBucket(t, tt.history, objects)
+			pool := roundPool{state: newBucketState(bucket)}
+
+			left, err := pool.available(ctx, []int{25, 26, 27})
+			if err != nil {
+				t.Fatalf("available failed: %v", err)
+			}
+			if !reflect.DeepEqual(left, tt.wantLeft) {
+				t.Fatalf("available = %v, want %v", left, tt.wantLeft)
+			}
+
+			selection := pool.take(left[0])
+			if selection.Progress != tt.wantProgress {
+				t.Errorf("progress = %q, want %q", selection.Progress, tt.wantProgress)
+			}
+
+			if err := pool.Commit(ctx, selection); err != nil {
+				t.Fatalf("Commit failed: %v", err)
+			}
+
+			var saved round
+			found, err := newBucketState(bucket).readState(ctx, roundObject, &saved)
+			if err != nil {
+				t.Fatalf("readState failed: %v", err)
+			}
+			if tt.wantRound == nil {
+				if found && tt.round == nil {
+					t.Errorf("%s = %+v, want none", roundObject, saved)
+				}
+				return
+			}
+			if !reflect.DeepEqual(saved, *tt.wantRound) {
+				t.Errorf("%s = %+v, want %+v", roundObject, saved, *tt.wantRound)
+			}
+		})
+	}
+}
+
+// TestRandomSelectorRounds publishes through two rounds, one species as a
+// form, and checks that each species comes up once per round.
+func TestRandomSelectorRounds(t *testing.T) {
+	ctx := context.Background()
+	bucket := newTestBucket(t, []publishedVariety{{pokemonID: 10100, speciesID: 26}}, nil)
+	eligible := []int{25, 26, 27, 28}
+
+	counts := map[int]int{}
+	for day := 0; day < 7; day++ {
+		selector := &randomSelector{roundPool: roundPool{state: newBucketState(bucket)}}
+
+		selection, err := selector.Next(ctx, time.Time{}, eligible, rand.New(rand.NewSource(uint64(day))))
+		if err != nil {
+			t.Fatalf("day %d: Next failed: %v", day, err)
+		}
+		counts[selection.SpeciesID]++
+
+		publishedAt := time.Date(2026, 10, 18+day, 15, 0, 0, 0, time.UTC)
+		entry := historyEntry{PublishedAt: &publishedAt, PokemonID: selection.SpeciesID, SpeciesID: selection.SpeciesID}
+		if err := updateHistory(ctx, bucket, entry); err != nil {
+			t.Fatalf("updateHistory failed: %v", err)
+		}
+		if err := selector.Commit(ctx, selection); err != nil {
+			t.Fatalf("day %d: Commit failed: %v", day, err)
+		}
+	}
+
+	// 3 species are left of the first round, then the 4 of the second
+	want := map[int]int{25: 2, 26: 1, 27: 2, 28: 2}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("published %v, want %v", counts, want)
+	}
+}
+
+func TestWeightedSelectorSkipsZeroWeights(t *testing.T) {
+	ctx := context.Background()
+	bucket := newTestBucket(t, []publishedVariety{{pokemonID: 25, speciesID: 25}}, nil)
+	selector := &weightedSelector{roundPool: roundPool{state: newBucketState(bucket)}, weights: map[int]float64{26: 0}}
+
+	// 25 is published and 26 is never picked, so 27 is all that is left
+	// before a new round starts.
+	for seed := uint64(0); seed < 10; seed++ {
+		selection, err := selector.Next(ctx, time.Time{}, []int{25, 26, 27}, rand.New(rand.NewSource(seed)))
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if selection.SpeciesID != 27 {
+			t.Fatalf("Next picked %d, want 27", selection.SpeciesID)
+		}
+	}
+
+	if _, err := selector.Next(ctx, time.Time{}, []int{26}, rand.New(rand.NewSource(1))); err == nil {
+		t.Errorf("Next with only zero weights succeeded, want an error")
+	}
+}
+
+func TestSelectorsWithoutEligibleSpecies(t *testing.T) {
+	state := newBucketState(memory.NewBucket())
+
+	tests := []struct {
+		name     string
+		selector Selector
+	}{
+		{name: "deck", selector: &deckSelector{state: state}},
+		{name: "random", selector: &randomSelector{roundPool: roundPool{state: state}}},
+		{name: "sequential", selector: &sequentialSelector{state: state}},
+		{name: "weighted", selector: &weightedSelector{roundPool: roundPool{state: state}}},
+		{name: "list", selector: &listSelector{state: state, ids: []int{25}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.selector.Next(context.Background(), time.Time{}, []int{}, rand.New(rand.NewSource(1))); err == nil {
+				t.Errorf("Next succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package pokemon
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"golang.org/x/exp/rand"
+)
+
+const defaultShinyOdds = 4096
+
+// ShinyConfig controls how often the shiny artwork is featured. Odds are the
+// N in a 1/N chance per publish.
+type ShinyConfig struct {
+	Odds int
+
+	// EventOdds replace Odds between EventStart and EventEnd (inclusive),
+	// e.g. to boost shiny appearances during a community event.
+	EventOdds  int
+	EventStart time.Time
+	EventEnd   time.Time
+}
+
+// ShinyConfigFromEnv reads SHINY_ODDS and the optional SHINY_EVENT_ODDS,
+// SHINY_EVENT_START and SHINY_EVENT_END (dates formatted as 2006-01-02).
+func ShinyConfigFromEnv() (ShinyConfig, error) {
+	cfg := ShinyConfig{Odds: defaultShinyOdds}
+
+	if odds := os.Getenv("SHINY_ODDS"); odds != "" {
+		parsed, err := parseOdds(odds)
+		if err != nil {
+			return cfg, fmt.Errorf("SHINY_ODDS is invalid: %w", err)
+		}
+		cfg.Odds = parsed
+	}
+
+	eventOdds := os.Getenv("SHINY_EVENT_ODDS")
+	if eventOdds == "" {
+		return cfg, nil
+	}
+
+	parsed, err := parseOdds(eventOdds)
+	if err != nil {
+		return cfg, fmt.Errorf("SHINY_EVENT_ODDS is invalid: %w", err)
+	}
+	cfg.EventOdds = parsed
+
+	cfg.EventStart, err = time.Parse(time.DateOnly, os.Getenv("SHINY_EVENT_START"))
+	if err != nil {
+		return cfg, fmt.Errorf("SHINY_EVENT_START is required with SHINY_EVENT_ODDS: %w", err)
+	}
+
+	cfg.EventEnd, err = time.Parse(time.DateOnly, os.Getenv("SHINY_EVENT_END"))
+	if err != nil {
+		return cfg, fmt.Errorf("SHINY_EVENT_END is required with SHINY_EVENT_ODDS: %w", err)
+	}
+
+	return cfg, nil
+}
+
+func parseOdds(odds string) (int, error) {
+	parsed, err := strconv.Atoi(odds)
+	if err != nil {
+		return 0, err
+	}
+
+	if parsed < 1 {
+		return 0, fmt.Errorf("odds must be at least 1, got %d", parsed)
+	}
+
+	return parsed, nil
+}
+
+// oddsOn returns the odds that apply on date, the calendar day in the
+// schedule's time zone formatted as 2006-01-02.
+func (cfg ShinyConfig) oddsOn(date string) int {
+	if cfg.EventOdds == 0 {
+		return cfg.Odds
+	}
+
+	day, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return cfg.Odds
+	}
+	if !day.Before(cfg.EventStart) && !day.After(cfg.EventEnd) {
+		return cfg.EventOdds
+	}
+
+	return cfg.Odds
+}
+
+// rollShiny decides whether the post of date features the shiny artwork.
+func (cfg ShinyConfig) rollShiny(date string, rng *rand.Rand) bool {
+	return rng.Intn(cfg.oddsOn(date)) == 0
+}
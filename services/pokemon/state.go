@@ -0,0 +1,187 @@
+package pokemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+)
+
+// selectionState is what selection reads and updates: the publish history
+// and the JSON state objects of stateful selectors. isPublished looks up a
+// pokemon ID, while isSpeciesPublished is true once any variety of the
+// species has been published.
+type selectionState interface {
+	isPublished(ctx context.Context, id int) (bool, error)
+	isSpeciesPublished(ctx context.Context, speciesID int) (bool, error)
+	readState(ctx context.Context, object string, v any) (bool, error)
+	writeState(ctx context.Context, object string, v any) error
+}
+
+// bucketState is the selection state stored in the history bucket. The
+// history is listed once, on the first lookup, so selection does not need a
+// request per candidate.
+type bucketState struct {
+	bucket    cloud.FileBucket
+	published map[int]bool
+	species   map[int]bool
+}
+
+func newBucketState(bucket cloud.FileBucket) *bucketState {
+	return &bucketState{bucket: bucket}
+}
+
+func (s *bucketState) isPublished(ctx context.Context, id int) (bool, error) {
+	if err := s.loadHistory(ctx); err != nil {
+		return false, err
+	}
+
+	return alreadyPublished(id, s.published), nil
+}
+
+func (s *bucketState) isSpeciesPublished(ctx context.Context, speciesID int) (bool, error) {
+	if err := s.loadHistory(ctx); err != nil {
+		return false, err
+	}
+
+	if s.species == nil {
+		species, err := listPublishedSpecies(ctx, s.bucket, s.published)
+		if err != nil {
+			return false, err
+		}
+		s.species = species
+	}
+
+	return s.species[speciesID], nil
+}
+
+func (s *bucketState) loadHistory(ctx context.Context) error {
+	if s.published != nil {
+		return nil
+	}
+
+	published, err := listHistory(ctx, s.bucket)
+	if err != nil {
+		return err
+	}
+	s.published = published
+
+	return nil
+}
+
+func alreadyPublished(pokemonNum int, previouslyPublished map[int]bool) bool {
+	return previouslyPublished[pokemonNum]
+}
+
+// readState decodes the JSON object into v, reporting false if it does not
+// exist yet.
+func (s *bucketState) readState(ctx context.Context, object string, v any) (bool, error) {
+	content, err := s.bucket.ReadFile(ctx, object)
+	if err != nil {
+		if errors.Is(err, cloud.ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := json.Unmarshal(content, v); err != nil {
+		return false, fmt.Errorf("%s is not a valid JSON: %w", object, err)
+	}
+
+	return true, nil
+}
+
+func (s *bucketState) writeState(ctx context.Context, object string, v any) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", object, err)
+	}
+
+	return s.bucket.CreateFile(ctx, object, content)
+}
+
+// previewState reads through to the bucket but keeps every change in memory,
+// so upcoming days can be simulated without touching the real state.
+type previewState struct {
+	*bucketState
+	published map[int]bool
+	species   map[int]bool
+	objects   map[string][]byte
+}
+
+func newPreviewState(bucket cloud.FileBucket) *previewState {
+	return &previewState{
+		bucketState: newBucketState(bucket),
+		published:   make(map[int]bool),
+		species:     make(map[int]bool),
+		objects:     make(map[string][]byte),
+	}
+}
+
+// markPublished records entry as if it had been written to the history, so
+// later days see what a real publish would have left behind.
+func (s *previewState) markPublished(entry historyEntry) {
+	s.published[entry.PokemonID] = true
+	if entry.SpeciesID != 0 {
+		s.species[entry.SpeciesID] = true
+	}
+}
+
+func (s *previewState) isPublished(ctx context.Context, id int) (bool, error) {
+	if s.published[id] {
+		return true, nil
+	}
+
+	return s.bucketState.isPublished(ctx, id)
+}
+
+func (s *previewState) isSpeciesPublished(ctx context.Context, speciesID int) (bool, error) {
+	if s.species[speciesID] {
+		return true, nil
+	}
+
+	return s.bucketState.isSpeciesPublished(ctx, speciesID)
+}
+
+func (s *previewState) readState(ctx context.Context, object string, v any) (bool, error) {
+	content, ok := s.objects[object]
+	if !ok {
+		return s.bucketState.readState(ctx, object, v)
+	}
+
+	if err := json.Unmarshal(content, v); err != nil {
+		return false, fmt.Errorf("%s is not a valid JSON: %w", object, err)
+	}
+
+	return true, nil
+}
+
+func (s *previewState) writeState(ctx context.Context, object string, v any) error {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", object, err)
+	}
+
+	s.objects[object] = content
+	return nil
+}
+
+// unpublishedSpecies filters out the species with any variety in the
+// history.
+func unpublishedSpecies(ctx context.Context, state selectionState, ids []int) ([]int, error) {
+	unpublished := []int{}
+
+	for _, id := range ids {
+		published, err := state.isSpeciesPublished(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check if species #%d has been published already: %w", id, err)
+		}
+		if !published {
+			unpublished = append(unpublished, id)
+		}
+	}
+
+	return unpublished, nil
+}
@@ -0,0 +1,39 @@
+package pokemon
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestPreviewStateMatchesHistory checks that a simulated publish is seen
+// the same way as the history entry Publish writes for it.
+func TestPreviewStateMatchesHistory(t *testing.T) {
+	ctx := context.Background()
+	planned := plan{Selection: Selection{SpeciesID: 26}, PokemonID: 10100}
+	entry := newHistoryEntry(time.Date(2026, 10, 18, 15, 0, 0, 0, time.UTC), planned, draft{}, sentPost{})
+
+	preview := newPreviewState(newTestBucket(t, nil, nil))
+	preview.markPublished(entry)
+
+	published := newBucketState(newTestBucket(t, []publishedVariety{{pokemonID: 10100, speciesID: 26}}, nil))
+
+	for _, id := range []int{26, 10100} {
+		for name, state := range map[string]selectionState{"preview": preview, "history": published} {
+			gotVariety, err := state.isPublished(ctx, id)
+			if err != nil {
+				t.Fatalf("%s: isPublished failed: %v", name, err)
+			}
+			gotSpecies, err := state.isSpeciesPublished(ctx, id)
+			if err != nil {
+				t.Fatalf("%s: isSpeciesPublished failed: %v", name, err)
+			}
+
+			wantVariety, wantSpecies := id == 10100, id == 26
+			if gotVariety != wantVariety || gotSpecies != wantSpecies {
+				t.Errorf("%s: #%d isPublished = %v, isSpeciesPublished = %v, want %v, %v",
+					name, id, gotVariety, gotSpecies, wantVariety, wantSpecies)
+			}
+		}
+	}
+}
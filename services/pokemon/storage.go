@@ -0,0 +1,132 @@
+package pokemon
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud/aws"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud/gcp"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud/local"
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud/memory"
+)
+
+// supported values of STORAGE_BACKEND
+const (
+	StorageGCS    = "gcs"
+	StorageS3     = "s3"
+	StorageLocal  = "local"
+	StorageMemory = "memory"
+)
+
+// StorageConfig chooses where the history and selection state are kept.
+type StorageConfig struct {
+	Backend string
+
+	// Dir is the directory of the local backend.
+	Dir string
+
+	GCS gcp.Config
+	S3  aws.Config
+}
+
+// StorageConfigFromEnv reads STORAGE_BACKEND, which defaults to gcs,
+// STORAGE_DIR, the directory of the local backend (default "bucket"), and
+// STORAGE_TIMEOUT, the deadline of each call to GCS or S3, e.g. "10s". The
+// GCS and S3 settings are read whichever backend is chosen.
+func StorageConfigFromEnv() (StorageConfig, error) {
+	cfg := StorageConfig{
+		Backend: os.Getenv("STORAGE_BACKEND"),
+		Dir:     os.Getenv("STORAGE_DIR"),
+		GCS:     gcp.ConfigFromEnv(),
+		S3:      aws.ConfigFromEnv(),
+	}
+
+	switch cfg.Backend {
+	case "":
+		cfg.Backend = StorageGCS
+	case StorageGCS, StorageS3, StorageLocal, StorageMemory:
+	default:
+		return cfg, fmt.Errorf("STORAGE_BACKEND must be one of %s, %s, %s or %s, got %q",
+			StorageGCS, StorageS3, StorageLocal, StorageMemory, cfg.Backend)
+	}
+
+	if cfg.Dir == "" {
+		cfg.Dir = "bucket"
+	}
+
+	if raw := os.Getenv("STORAGE_TIMEOUT"); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err != nil || timeout <= 0 {
+			return cfg, fmt.Errorf("STORAGE_TIMEOUT must be a positive duration, e.g. 10s, got %q", raw)
+		}
+		cfg.GCS.Timeout = timeout
+		cfg.S3.Timeout = timeout
+	}
+
+	return cfg, nil
+}
+
+// NewBucket validates the configuration of the chosen backend and returns
+// its bucket, so a misconfigured backend fails before anything is published.
+func NewBucket(ctx context.Context, cfg StorageConfig) (cloud.FileBucket, error) {
+	switch cfg.Backend {
+	case StorageLocal:
+		return local.NewBucket(cfg.Dir)
+	case StorageMemory:
+		return memory.NewBucket(), nil
+	case StorageS3:
+		bucket, err := aws.NewBucket(cfg.S3)
+		if err != nil {
+			return nil, fmt.Errorf("invalid s3 storage config: %w", err)
+		}
+		return bucket, nil
+	default:
+		bucket, err := gcp.NewBucket(ctx, cfg.GCS)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gcs storage config: %w", err)
+		}
+		return bucket, nil
+	}
+}
+
+// sharedBucket is the bucket of the environment's storage config, kept for
+// the life of the process so that warm function instances reuse its client.
+var sharedBucket struct {
+	sync.Mutex
+	bucket cloud.FileBucket
+}
+
+func newBucketFromEnv(ctx context.Context) (cloud.FileBucket, error) {
+	sharedBucket.Lock()
+	defer sharedBucket.Unlock()
+
+	if sharedBucket.bucket != nil {
+		return sharedBucket.bucket, nil
+	}
+
+	cfg, err := StorageConfigFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load storage config: %w", err)
+	}
+
+	// the client outlives the call it is created in.
+	bucket, err := NewBucket(context.WithoutCancel(ctx), cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedBucket.bucket = bucket
+	return bucket, nil
+}
+
+// SetUpStorage creates the bucket of the environment's storage config, so
+// that a function instance with a misconfigured backend fails as it starts
+// rather than on its first publish. Later calls reuse the bucket.
+func SetUpStorage(ctx context.Context) error {
+	_, err := newBucketFromEnv(ctx)
+	return err
+}
@@ -0,0 +1,433 @@
+package pokemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/rand"
+	"gopkg.in/yaml.v3"
+)
+
+// supported values of a rule's dex_from_date
+const (
+	DexFromDayOfMonth = "day_of_month"
+	DexFromDayOfYear  = "day_of_year"
+	DexFromMonthDay   = "month_day"
+)
+
+// rulesFile is the format of RULES_FILE, in JSON or, for files ending in
+// .yaml or .yml, YAML.
+type rulesFile struct {
+	Rules []Rule `json:"rules" yaml:"rules"`
+}
+
+// Rule themes the days it matches. A rule matches a day when every condition
+// it sets holds; a rule without conditions matches every day. The rule then
+// restricts the pick to its pinned species, its types or the dex number
+// derived from the date, and changes the post text. A rule without filters
+// only changes the post text.
+type Rule struct {
+	Name string `json:"name" yaml:"name"`
+
+	// conditions
+	Weekdays []string `json:"weekdays" yaml:"weekdays"`
+	Months   []int    `json:"months" yaml:"months"`
+	// From and To are an inclusive "MM-DD" range that may wrap around the
+	// new year, e.g. "12-20" to "01-06".
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+	// Dates are either "YYYY-MM-DD" for a single day or "MM-DD" for every
+	// year.
+	Dates []string `json:"dates" yaml:"dates"`
+
+	// filters
+	Pin         []int    `json:"pin" yaml:"pin"`
+	Types       []string `json:"types" yaml:"types"`
+	RotateTypes []string `json:"rotate_types" yaml:"rotate_types"`
+	DexFromDate string   `json:"dex_from_date" yaml:"dex_from_date"`
+
+	// post text; {type} is replaced by the rotated type
+	Intro    string   `json:"intro" yaml:"intro"`
+	Hashtags []string `json:"hashtags" yaml:"hashtags"`
+
+	weekdays map[time.Weekday]bool
+}
+
+// Theme is what a matched rule changes about the post.
+type Theme struct {
+	Name     string
+	Intro    string
+	Hashtags []string
+}
+
+// loadRulesFromEnv reads the rules in RULES_FILE. There are no rules if it is
+// not set.
+func loadRulesFromEnv() ([]Rule, error) {
+	path := os.Getenv("RULES_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	var file rulesFile
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(content, &file); err != nil {
+			return nil, fmt.Errorf("rules file is not a valid YAML: %w", err)
+		}
+	default:
+		if err := json.Unmarshal(content, &file); err != nil {
+			return nil, fmt.Errorf("rules file is not a valid JSON: %w", err)
+		}
+	}
+
+	for i := range file.Rules {
+		if err := file.Rules[i].validate(); err != nil {
+			return nil, fmt.Errorf("rule %d (%q) of rules file is invalid: %w", i+1, file.Rules[i].Name, err)
+		}
+	}
+
+	return file.Rules, nil
+}
+
+func (r *Rule) validate() error {
+	r.weekdays = make(map[time.Weekday]bool)
+	for _, name := range r.Weekdays {
+		weekday, ok := parseWeekday(name)
+		if !ok {
+			return fmt.Errorf("unknown weekday %q", name)
+		}
+		r.weekdays[weekday] = true
+	}
+
+	for _, month := range r.Months {
+		if month < 1 || month > 12 {
+			return fmt.Errorf("month must be between 1 and 12, got %d", month)
+		}
+	}
+
+	if (r.From == "") != (r.To == "") {
+		return fmt.Errorf("from and to must be set together")
+	}
+	for _, monthDay := range []string{r.From, r.To} {
+		if monthDay == "" {
+			continue
+		}
+		if _, err := time.Parse("01-02", monthDay); err != nil {
+			return fmt.Errorf("%q is not formatted as MM-DD", monthDay)
+		}
+	}
+
+	for _, date := range r.Dates {
+		if _, err := time.Parse(time.DateOnly, date); err == nil {
+			continue
+		}
+		if _, err := time.Parse("01-02", date); err != nil {
+			return fmt.Errorf("date %q is not formatted as YYYY-MM-DD or MM-DD", date)
+		}
+	}
+
+	for _, id := range r.Pin {
+		if id < 1 {
+			return fmt.Errorf("pinned species must be dex numbers, got %d", id)
+		}
+	}
+
+	filters := 0
+	for _, set := range []bool{len(r.Pin) > 0, len(r.Types) > 0, len(r.RotateTypes) > 0, r.DexFromDate != ""} {
+		if set {
+			filters++
+		}
+	}
+	if filters > 1 {
+		return fmt.Errorf("only one of pin, types, rotate_types and dex_from_date can be set")
+	}
+
+	switch r.DexFromDate {
+	case "", DexFromDayOfMonth, DexFromDayOfYear, DexFromMonthDay:
+	default:
+		return fmt.Errorf("dex_from_date must be one of %s, %s or %s, got %q",
+			DexFromDayOfMonth, DexFromDayOfYear, DexFromMonthDay, r.DexFromDate)
+	}
+
+	return nil
+}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(name, weekday.String()) || strings.EqualFold(name, weekday.String()[:3]) {
+			return weekday, true
+		}
+	}
+
+	return 0, false
+}
+
+// matches reports whether day, in the schedule's time zone, is themed by r.
+func (r *Rule) matches(day time.Time) bool {
+	if len(r.weekdays) > 0 && !r.weekdays[day.Weekday()] {
+		return false
+	}
+
+	if len(r.Months) > 0 && !containsInt(r.Months, int(day.Month())) {
+		return false
+	}
+
+	monthDay := day.Format("01-02")
+
+	// MM-DD strings sort in calendar order.
+	if r.From != "" {
+		if r.From <= r.To && (monthDay < r.From || monthDay > r.To) {
+			return false
+		}
+		if r.From > r.To && monthDay < r.From && monthDay > r.To {
+			return false
+		}
+	}
+
+	if len(r.Dates) > 0 {
+		matched := false
+		for _, date := range r.Dates {
+			if date == day.Format(time.DateOnly) || date == monthDay {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rotatedType returns the type of the week day falls in, moving through
+// RotateTypes in order.
+func (r *Rule) rotatedType(day time.Time) string {
+	if len(r.RotateTypes) == 0 {
+		return ""
+	}
+
+	date := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	week := int(date.Unix() / int64(7*24*time.Hour/time.Second))
+
+	return r.RotateTypes[week%len(r.RotateTypes)]
+}
+
+func (r *Rule) theme(day time.Time) Theme {
+	replacer := strings.NewReplacer("{type}", formatName(r.rotatedType(day)))
+
+	theme := Theme{Name: r.Name, Intro: replacer.Replace(r.Intro)}
+	for _, hashtag := range r.Hashtags {
+		theme.Hashtags = append(theme.Hashtags, replacer.Replace(hashtag))
+	}
+
+	return theme
+}
+
+// matchRule returns the first rule matching day.
+func matchRule(rules []Rule, day time.Time) (*Rule, bool) {
+	for i := range rules {
+		if rules[i].matches(day) {
+			return &rules[i], true
+		}
+	}
+
+	return nil, false
+}
+
+func containsInt(ids []int, id int) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pooledSelector is implemented by selectors that keep a pool of species
+// still to be published, so a themed pick can be taken out of it instead of
+// repeating a species early. available returns the pool as of the last call
+// to Next.
+type pooledSelector interface {
+	Selector
+	available(ctx context.Context, eligible []int) ([]int, error)
+	take(id int) Selection
+}
+
+// themedSelector applies the calendar rules on top of another selector. On
+// days without a matching rule, or when none of the species a rule allows
+// are left, the inner selector picks as usual.
+type themedSelector struct {
+	inner   Selector
+	rules   []Rule
+	api     PokeAPI
+	state   selectionState
+	filters listFilters
+
+	// commitInner is set when the inner selector made or took the pick and
+	// needs to move on once it is published.
+	commitInner bool
+}
+
+func (s *themedSelector) Next(ctx context.Context, day time.Time, eligible []int, rng *rand.Rand) (Selection, error) {
+	s.commitInner = true
+
+	rule, ok := matchRule(s.rules, day)
+	if !ok {
+		return s.inner.Next(ctx, day, eligible, rng)
+	}
+	theme := rule.theme(day)
+
+	candidates, filtered, err := s.candidates(ctx, rule, day, eligible)
+	if err != nil {
+		return Selection{}, fmt.Errorf("failed to apply rule %q: %w", rule.Name, err)
+	}
+
+	if !filtered {
+		selection, err := s.inner.Next(ctx, day, eligible, rng)
+		selection.Theme = &theme
+		return selection, err
+	}
+
+	pooled, isPooled := s.inner.(pooledSelector)
+
+	// the inner selector's own pick loads its pool, starting a new season or
+	// round if needed, and stands if the rule has no species left in it.
+	var pool []int
+	var fallback Selection
+	if isPooled {
+		if fallback, err = s.inner.Next(ctx, day, eligible, rng); err != nil {
+			return Selection{}, err
+		}
+		if pool, err = pooled.available(ctx, eligible); err != nil {
+			return Selection{}, err
+		}
+	}
+
+	// pinned species are published on their day even if they were published
+	// before.
+	available := candidates
+	if len(rule.Pin) == 0 {
+		if isPooled {
+			available = intersect(candidates, pool)
+		} else if available, err = unpublishedSpecies(ctx, s.state, candidates); err != nil {
+			return Selection{}, err
+		}
+	}
+
+	if len(available) == 0 {
+		if isPooled {
+			return fallback, nil
+		}
+		return s.inner.Next(ctx, day, eligible, rng)
+	}
+
+	id := available[rng.Intn(len(available))]
+
+	if isPooled && containsInt(pool, id) {
+		selection := pooled.take(id)
+		selection.Theme = &theme
+		return selection, nil
+	}
+
+	s.commitInner = false
+	return Selection{SpeciesID: id, Theme: &theme}, nil
+}
+
+// candidates returns the species rule allows on day, and whether the rule
+// filters them at all.
+func (s *themedSelector) candidates(ctx context.Context, rule *Rule, day time.Time, eligible []int) ([]int, bool, error) {
+	// pinned species do not need to be eligible, but the blocklist still
+	// applies to them.
+	if len(rule.Pin) > 0 {
+		pinned := []int{}
+		for _, id := range rule.Pin {
+			blocked, err := s.filters.blocksSpecies(ctx, s.api, id)
+			if err != nil {
+				return nil, true, err
+			}
+			if !blocked {
+				pinned = append(pinned, id)
+			}
+		}
+		return pinned, true, nil
+	}
+
+	if rule.DexFromDate != "" {
+		var id int
+		switch rule.DexFromDate {
+		case DexFromDayOfMonth:
+			id = day.Day()
+		case DexFromDayOfYear:
+			id = day.YearDay()
+		case DexFromMonthDay:
+			id = int(day.Month())*100 + day.Day()
+		}
+		return intersect([]int{id}, eligible), true, nil
+	}
+
+	types := rule.Types
+	if len(rule.RotateTypes) > 0 {
+		types = []string{rule.rotatedType(day)}
+	}
+	if len(types) == 0 {
+		return nil, false, nil
+	}
+
+	ofType := []int{}
+	for _, name := range types {
+		pokemonType, err := s.api.GetType(ctx, strings.ToLower(name))
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to get pokemon of type %s: %w", name, err)
+		}
+
+		// only default varieties share their ID with the species; forms
+		// with a different type than their species are not considered.
+		for _, pokemon := range pokemonType.Pokemon {
+			id, err := idFromURL(pokemon.Pokemon.URL)
+			if err != nil {
+				return nil, true, err
+			}
+			ofType = append(ofType, id)
+		}
+	}
+
+	return intersect(eligible, ofType), true, nil
+}
+
+func (s *themedSelector) Commit(ctx context.Context, selection Selection) error {
+	if !s.commitInner {
+		return nil
+	}
+
+	return s.inner.Commit(ctx, selection)
+}
+
+// intersect returns the IDs of a that are also in b, in the order of a.
+func intersect(a []int, b []int) []int {
+	inB := make(map[int]bool, len(b))
+	for _, id := range b {
+		inB[id] = true
+	}
+
+	ids := []int{}
+	for _, id := range a {
+		if inB[id] {
+			ids = append(ids, id)
+			delete(inB, id)
+		}
+	}
+
+	return ids
+}
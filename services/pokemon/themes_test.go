@@ -0,0 +1,154 @@
+package pokemon
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func day(date string) time.Time {
+	t, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestRuleMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+		day  string
+		want bool
+	}{
+		{name: "no conditions", rule: Rule{}, day: "2026-10-18", want: true},
+		{name: "weekday", rule: Rule{Weekdays: []string{"sunday"}}, day: "2026-10-18", want: true},
+		{name: "other weekday", rule: Rule{Weekdays: []string{"Mon", "tue"}}, day: "2026-10-18", want: false},
+		{name: "month", rule: Rule{Months: []int{10}}, day: "2026-10-18", want: true},
+		{name: "other month", rule: Rule{Months: []int{12}}, day: "2026-10-18", want: false},
+		{name: "range", rule: Rule{From: "10-01", To: "10-31"}, day: "2026-10-18", want: true},
+		{name: "range start", rule: Rule{From: "10-18", To: "10-31"}, day: "2026-10-18", want: true},
+		{name: "range end", rule: Rule{From: "10-01", To: "10-18"}, day: "2026-10-18", want: true},
+		{name: "before range", rule: Rule{From: "10-19", To: "10-31"}, day: "2026-10-18", want: false},
+		{name: "wrapping range in december", rule: Rule{From: "12-20", To: "01-06"}, day: "2026-12-24", want: true},
+		{name: "wrapping range on new year", rule: Rule{From: "12-20", To: "01-06"}, day: "2027-01-01", want: true},
+		{name: "wrapping range end", rule: Rule{From: "12-20", To: "01-06"}, day: "2027-01-06", want: true},
+		{name: "after wrapping range", rule: Rule{From: "12-20", To: "01-06"}, day: "2027-01-07", want: false},
+		{name: "before wrapping range", rule: Rule{From: "12-20", To: "01-06"}, day: "2026-12-19", want: false},
+		{name: "yearly date", rule: Rule{Dates: []string{"02-27", "10-18"}}, day: "2026-10-18", want: true},
+		{name: "single date", rule: Rule{Dates: []string{"2026-10-18"}}, day: "2026-10-18", want: true},
+		{name: "single date of another year", rule: Rule{Dates: []string{"2025-10-18"}}, day: "2026-10-18", want: false},
+		{name: "all conditions", rule: Rule{Weekdays: []string{"sunday"}, Months: []int{10}, Dates: []string{"10-18"}}, day: "2026-10-18", want: true},
+		{name: "one condition fails", rule: Rule{Weekdays: []string{"saturday"}, Months: []int{10}}, day: "2026-10-18", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.validate(); err != nil {
+				t.Fatalf("validate failed: %v", err)
+			}
+			if got := tt.rule.matches(day(tt.day)); got != tt.want {
+				t.Errorf("matches(%s) = %v, want %v", tt.day, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRotatedType(t *testing.T) {
+	rule := Rule{RotateTypes: []string{"fire", "water", "grass"}}
+
+	// the week changes between Wednesday and Thursday, since weeks are
+	// counted from the Unix epoch, a Thursday.
+	tests := []struct {
+		day  string
+		want string
+	}{
+		{day: "2026-10-14", want: "water"},
+		{day: "2026-10-15", want: "grass"},
+		{day: "2026-10-21", want: "grass"},
+		{day: "2026-10-22", want: "fire"},
+		{day: "2026-10-29", want: "water"},
+	}
+
+	for _, tt := range tests {
+		if got := rule.rotatedType(day(tt.day)); got != tt.want {
+			t.Errorf("rotatedType(%s) = %q, want %q", tt.day, got, tt.want)
+		}
+	}
+
+	if got := (&Rule{}).rotatedType(day("2026-10-18")); got != "" {
+		t.Errorf("rotatedType without types = %q, want none", got)
+	}
+}
+
+func TestLoadRulesFromEnv(t *testing.T) {
+	want := []Rule{
+		{Name: "Halloween", Dates: []string{"2026-10-31"}, Types: []string{"ghost"}, Hashtags: []string{"Halloween"}},
+		{Name: "Winter holidays", From: "12-20", To: "01-06", Types: []string{"ice"}},
+		{Name: "Type Tuesday", Weekdays: []string{"tuesday"}, RotateTypes: []string{"fire", "water"}, Intro: "It's {type} Tuesday!"},
+	}
+
+	tests := []struct {
+		file    string
+		content string
+		wantErr bool
+	}{
+		{
+			file: "rules.json",
+			content: `{"rules": [
+				{"name": "Halloween", "dates": ["2026-10-31"], "types": ["ghost"], "hashtags": ["Halloween"]},
+				{"name": "Winter holidays", "from": "12-20", "to": "01-06", "types": ["ice"]},
+				{"name": "Type Tuesday", "weekdays": ["tuesday"], "rotate_types": ["fire", "water"], "intro": "It's {type} Tuesday!"}
+			]}`,
+		},
+		{
+			file: "rules.yaml",
+			content: `rules:
+  - name: Halloween
+    dates: [2026-10-31]
+    types: [ghost]
+    hashtags: [Halloween]
+  - name: Winter holidays
+    from: "12-20"
+    to: "01-06"
+    types: [ice]
+  - name: Type Tuesday
+    weekdays: [tuesday]
+    rotate_types: [fire, water]
+    intro: "It's {type} Tuesday!"
+`,
+		},
+		{file: "rules.yml", content: "rules:\n  - name: [not a name\n", wantErr: true},
+		{file: "rules.json", content: `{"rules": [{"name": "Bad", "months": [13]}]}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("RULES_FILE", path)
+
+			rules, err := loadRulesFromEnv()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("loadRulesFromEnv succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadRulesFromEnv failed: %v", err)
+			}
+
+			for i := range rules {
+				rules[i].weekdays = nil
+			}
+			if !reflect.DeepEqual(rules, want) {
+				t.Errorf("loadRulesFromEnv = %+v, want %+v", rules, want)
+			}
+		})
+	}
+}
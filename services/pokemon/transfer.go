@@ -0,0 +1,296 @@
+package pokemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud"
+)
+
+// stateObjects are the selection state objects copied along with the history
+// by a migration.
+var stateObjects = []string{deckObject, sequentialObject, listObject, roundObject, filtersObject}
+
+// HistoryDiff lists the objects an import or migration writes, or would write
+// in a dry run. Objects that already hold the same content are left alone, so
+// running it again writes nothing.
+type HistoryDiff struct {
+	Added     []string
+	Updated   []string
+	Unchanged int
+}
+
+// ExportHistory writes every history record of bucket to w as JSONL, one
+// record per publish, in pokemon ID and then publish order, and returns the
+// number of records. Pokemon published only by older versions, which kept no
+// records, are exported from their latest history.
+func ExportHistory(ctx context.Context, bucket cloud.FileBucket, w io.Writer) (int, error) {
+	published, err := listHistory(ctx, bucket)
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]int, 0, len(published))
+	for id := range published {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	encoder := json.NewEncoder(w)
+	count := 0
+	for _, id := range ids {
+		records, err := listHistoryRecords(ctx, bucket, id)
+		if err != nil {
+			return count, err
+		}
+
+		if len(records) == 0 {
+			entry, err := readHistoryEntry(ctx, bucket, id)
+			if err != nil {
+				return count, err
+			}
+			records = append(records, entry)
+		}
+
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return count, fmt.Errorf("failed to write history of pokemon #%d: %w", id, err)
+			}
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// ImportHistory writes the JSONL history records read from r into bucket.
+// Records must set pokemon_id. Each record with a publish time gets its own
+// record object, and the latest record of each pokemon becomes its history.
+// With dryRun, nothing is written and the diff only reports what would be.
+func ImportHistory(ctx context.Context, bucket cloud.FileBucket, r io.Reader, dryRun bool) (HistoryDiff, error) {
+	published, err := listHistory(ctx, bucket)
+	if err != nil {
+		return HistoryDiff{}, err
+	}
+
+	importer := historyImporter{
+		bucket:    bucket,
+		published: published,
+		latest:    make(map[int]historyEntry),
+		written:   make(map[string]historyEntry),
+		dryRun:    dryRun,
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var entry historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return importer.diff, fmt.Errorf("line %d is not a valid history record: %w", line, err)
+		}
+		if entry.PokemonID <= 0 {
+			return importer.diff, fmt.Errorf("line %d has no pokemon_id", line)
+		}
+
+		if err := importer.importEntry(ctx, entry); err != nil {
+			return importer.diff, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return importer.diff, fmt.Errorf("failed to read history records: %w", err)
+	}
+
+	return importer.diff, nil
+}
+
+// historyImporter keeps track of the latest history of each pokemon while
+// importing, so records can come in any order and a dry run reports the same
+// diff as a real import.
+type historyImporter struct {
+	bucket    cloud.FileBucket
+	published map[int]bool
+	latest    map[int]historyEntry
+	written   map[string]historyEntry
+	dryRun    bool
+	diff      HistoryDiff
+}
+
+func (i *historyImporter) importEntry(ctx context.Context, entry historyEntry) error {
+	if record, ok := historyRecordObject(entry); ok {
+		if err := i.importObject(ctx, record, entry); err != nil {
+			return err
+		}
+	}
+
+	latest, ok := i.latest[entry.PokemonID]
+	if !ok && i.published[entry.PokemonID] {
+		var err error
+		if latest, err = readHistoryEntry(ctx, i.bucket, entry.PokemonID); err != nil {
+			return err
+		}
+		ok = true
+	}
+	if ok && publishedBefore(entry, latest) {
+		return nil
+	}
+
+	if err := i.importObject(ctx, historyObject(entry.PokemonID), entry); err != nil {
+		return err
+	}
+	i.latest[entry.PokemonID] = entry
+
+	return nil
+}
+
+// publishedBefore reports whether a was published before b. Entries of older
+// versions have no publish time and count as published before any other.
+func publishedBefore(a, b historyEntry) bool {
+	if b.PublishedAt == nil {
+		return false
+	}
+
+	return a.PublishedAt == nil || a.PublishedAt.Before(*b.PublishedAt)
+}
+
+// importObject writes entry to object unless it already holds the same
+// entry. Objects written earlier in the import are compared with what was
+// written rather than read back, since a dry run writes nothing.
+func (i *historyImporter) importObject(ctx context.Context, object string, entry historyEntry) error {
+	existing, err := i.readObject(ctx, object, entry.PokemonID)
+	switch {
+	case errors.Is(err, cloud.ErrNotFound):
+		i.diff.Added = append(i.diff.Added, object)
+	case err != nil:
+		return err
+	default:
+		// both sides are re-encoded, so records that only differ in field
+		// order or whitespace compare equal.
+		same, err := sameJSON(existing, entry)
+		if err != nil {
+			return fmt.Errorf("failed to compare %s: %w", object, err)
+		}
+		if same {
+			i.diff.Unchanged++
+			return nil
+		}
+		i.diff.Updated = append(i.diff.Updated, object)
+	}
+
+	i.written[object] = entry
+	if i.dryRun {
+		return nil
+	}
+
+	if err := writeHistoryObject(ctx, i.bucket, object, entry); err != nil {
+		return fmt.Errorf("failed to write %s: %w", object, err)
+	}
+
+	return nil
+}
+
+// readObject returns the entry object holds, as written earlier in the
+// import or read from the bucket.
+func (i *historyImporter) readObject(ctx context.Context, object string, pokemonID int) (historyEntry, error) {
+	if entry, ok := i.written[object]; ok {
+		return entry, nil
+	}
+
+	content, err := i.bucket.ReadFile(ctx, object)
+	if errors.Is(err, cloud.ErrNotFound) {
+		return historyEntry{}, err
+	}
+	if err != nil {
+		return historyEntry{}, fmt.Errorf("failed to read %s: %w", object, err)
+	}
+
+	return decodeHistoryEntry(content, pokemonID)
+}
+
+func sameJSON(a, b any) (bool, error) {
+	encodedA, err := json.Marshal(a)
+	if err != nil {
+		return false, err
+	}
+	encodedB, err := json.Marshal(b)
+	if err != nil {
+		return false, err
+	}
+
+	return bytes.Equal(encodedA, encodedB), nil
+}
+
+// MigrateHistory copies the history and the selection state objects from one
+// bucket to another, so the bot can move backends without repeating pokemon
+// or restarting its deck. Day leases are not copied.
+func MigrateHistory(ctx context.Context, from, to cloud.FileBucket, dryRun bool) (HistoryDiff, error) {
+	var records bytes.Buffer
+	if _, err := ExportHistory(ctx, from, &records); err != nil {
+		return HistoryDiff{}, fmt.Errorf("failed to export history: %w", err)
+	}
+
+	diff, err := ImportHistory(ctx, to, &records, dryRun)
+	if err != nil {
+		return diff, fmt.Errorf("failed to import history: %w", err)
+	}
+
+	for _, object := range stateObjects {
+		if err := migrateObject(ctx, from, to, object, dryRun, &diff); err != nil {
+			return diff, err
+		}
+	}
+
+	return diff, nil
+}
+
+func migrateObject(ctx context.Context, from, to cloud.FileBucket, object string, dryRun bool, diff *HistoryDiff) error {
+	exists, err := from.FileExists(ctx, object)
+	if err != nil {
+		return fmt.Errorf("failed to check if %s exists: %w", object, err)
+	}
+	if !exists {
+		return nil
+	}
+
+	content, err := from.ReadFile(ctx, object)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", object, err)
+	}
+
+	exists, err = to.FileExists(ctx, object)
+	if err != nil {
+		return fmt.Errorf("failed to check if %s exists: %w", object, err)
+	}
+	if !exists {
+		diff.Added = append(diff.Added, object)
+	} else {
+		existing, err := to.ReadFile(ctx, object)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", object, err)
+		}
+		if bytes.Equal(existing, content) {
+			diff.Unchanged++
+			return nil
+		}
+		diff.Updated = append(diff.Updated, object)
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	if err := to.CreateFile(ctx, object, content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", object, err)
+	}
+
+	return nil
+}
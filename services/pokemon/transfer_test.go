@@ -0,0 +1,186 @@
+package pokemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rickrollrumble/random-pokemon-publisher/services/cloud/memory"
+)
+
+// historyJSONL encodes entries as an import file.
+func historyJSONL(t *testing.T, entries []historyEntry) string {
+	t.Helper()
+
+	var b strings.Builder
+	encoder := json.NewEncoder(&b)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			t.Fatalf("failed to encode %+v: %v", entry, err)
+		}
+	}
+
+	return b.String()
+}
+
+func testHistory() []historyEntry {
+	at := func(day int) *time.Time {
+		publishedAt := time.Date(2026, 10, day, 15, 0, 0, 0, time.UTC)
+		return &publishedAt
+	}
+
+	return []historyEntry{
+		// published by a version that kept no publish time
+		{PokemonID: 4, Name: "Charmander"},
+		{PublishedAt: at(1), PokemonID: 25, SpeciesID: 25, Name: "Pikachu"},
+		{PublishedAt: at(2), PokemonID: 10100, SpeciesID: 26, Name: "Raichu", Form: "alola"},
+		{PublishedAt: at(3), PokemonID: 25, SpeciesID: 25, Name: "Pikachu", Shiny: true},
+	}
+}
+
+func TestImportHistory(t *testing.T) {
+	ctx := context.Background()
+	bucket := memory.NewBucket()
+	entries := testHistory()
+
+	diff, err := ImportHistory(ctx, bucket, strings.NewReader(historyJSONL(t, entries)), false)
+	if err != nil {
+		t.Fatalf("ImportHistory failed: %v", err)
+	}
+
+	// three record objects and the latest history of three pokemon, that
+	// of pikachu written twice
+	if len(diff.Added) != 6 || len(diff.Updated) != 1 {
+		t.Errorf("diff = %+v, want 6 added and 1 updated", diff)
+	}
+
+	latest, err := readHistoryEntry(ctx, bucket, 25)
+	if err != nil {
+		t.Fatalf("readHistoryEntry failed: %v", err)
+	}
+	if !latest.Shiny {
+		t.Errorf("history of pokemon #25 = %+v, want the latest, shiny record", latest)
+	}
+
+	var exported bytes.Buffer
+	count, err := ExportHistory(ctx, bucket, &exported)
+	if err != nil {
+		t.Fatalf("ExportHistory failed: %v", err)
+	}
+	if count != 4 {
+		t.Errorf("exported %d records, want 4", count)
+	}
+}
+
+// TestImportHistoryIsIdempotent imports the same records again, in the same
+// and in reverse order, and checks that nothing is written.
+func TestImportHistoryIsIdempotent(t *testing.T) {
+	ctx := context.Background()
+	entries := testHistory()
+	reversed := make([]historyEntry, len(entries))
+	for i, entry := range entries {
+		reversed[len(entries)-1-i] = entry
+	}
+
+	for _, order := range []struct {
+		name    string
+		entries []historyEntry
+	}{
+		{name: "same order", entries: entries},
+		{name: "reverse order", entries: reversed},
+	} {
+		t.Run(order.name, func(t *testing.T) {
+			bucket := memory.NewBucket()
+			if _, err := ImportHistory(ctx, bucket, strings.NewReader(historyJSONL(t, entries)), false); err != nil {
+				t.Fatalf("first import failed: %v", err)
+			}
+			before := bucketObjects(t, bucket)
+
+			diff, err := ImportHistory(ctx, bucket, strings.NewReader(historyJSONL(t, order.entries)), false)
+			if err != nil {
+				t.Fatalf("second import failed: %v", err)
+			}
+			if len(diff.Added) != 0 || len(diff.Updated) != 0 {
+				t.Errorf("second import diff = %+v, want nothing added or updated", diff)
+			}
+			if diff.Unchanged == 0 {
+				t.Errorf("second import left nothing unchanged")
+			}
+
+			if after := bucketObjects(t, bucket); !reflect.DeepEqual(after, before) {
+				t.Errorf("second import changed the bucket from %v to %v", before, after)
+			}
+		})
+	}
+}
+
+func TestImportHistoryDryRun(t *testing.T) {
+	ctx := context.Background()
+	content := historyJSONL(t, testHistory())
+
+	bucket := memory.NewBucket()
+	dryRun, err := ImportHistory(ctx, bucket, strings.NewReader(content), true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if objects := bucketObjects(t, bucket); len(objects) != 0 {
+		t.Errorf("dry run wrote %v", objects)
+	}
+
+	imported, err := ImportHistory(ctx, bucket, strings.NewReader(content), false)
+	if err != nil {
+		t.Fatalf("ImportHistory failed: %v", err)
+	}
+	if !reflect.DeepEqual(dryRun, imported) {
+		t.Errorf("dry run diff = %+v, want %+v as imported", dryRun, imported)
+	}
+}
+
+// TestImportHistoryOrder checks that records imported in reverse order leave
+// the same history as in publish order.
+func TestImportHistoryOrder(t *testing.T) {
+	ctx := context.Background()
+	entries := testHistory()
+	reversed := make([]historyEntry, len(entries))
+	for i, entry := range entries {
+		reversed[len(entries)-1-i] = entry
+	}
+
+	inOrder, inReverse := memory.NewBucket(), memory.NewBucket()
+	if _, err := ImportHistory(ctx, inOrder, strings.NewReader(historyJSONL(t, entries)), false); err != nil {
+		t.Fatalf("import in order failed: %v", err)
+	}
+	if _, err := ImportHistory(ctx, inReverse, strings.NewReader(historyJSONL(t, reversed)), false); err != nil {
+		t.Fatalf("import in reverse failed: %v", err)
+	}
+
+	if got, want := bucketObjects(t, inReverse), bucketObjects(t, inOrder); !reflect.DeepEqual(got, want) {
+		t.Errorf("import in reverse wrote %v, want %v", got, want)
+	}
+}
+
+// bucketObjects returns the content of every object in bucket.
+func bucketObjects(t *testing.T, bucket *memory.Bucket) map[string]string {
+	t.Helper()
+
+	ctx := context.Background()
+	objects, err := bucket.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+
+	contents := make(map[string]string)
+	for _, object := range objects {
+		content, err := bucket.ReadFile(ctx, object)
+		if err != nil {
+			t.Fatalf("ReadFile(%q) failed: %v", object, err)
+		}
+		contents[object] = string(content)
+	}
+
+	return contents
+}
@@ -1,10 +1,23 @@
 package pokemon
 
 type RespPokemon struct {
-	Name    string  `json:"name"`
-	Sprites Sprites `json:"sprites"`
-	Stats   []Stats `json:"stats"`
-	Types   []Types `json:"types"`
+	ID        int         `json:"id"`
+	Name      string      `json:"name"`
+	Species   Species     `json:"species"`
+	Forms     []Form      `json:"forms"`
+	Abilities []Abilities `json:"abilities"`
+	Sprites   Sprites     `json:"sprites"`
+	Stats     []Stats     `json:"stats"`
+	Types     []Types     `json:"types"`
+}
+type Ability struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+type Abilities struct {
+	Ability  Ability `json:"ability"`
+	IsHidden bool    `json:"is_hidden"`
+	Slot     int     `json:"slot"`
 }
 type OfficialArtwork struct {
 	FrontDefault string `json:"front_default"`
@@ -35,8 +48,33 @@ type Types struct {
 	Slot int  `json:"slot"`
 	Type Type `json:"type"`
 }
+type Form struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
 type RespPokemonSpecies struct {
+	Name              string              `json:"name"`
+	IsLegendary       bool                `json:"is_legendary"`
+	IsMythical        bool                `json:"is_mythical"`
+	Names             []Names             `json:"names"`
 	FlavorTextEntries []FlavorTextEntries `json:"flavor_text_entries"`
+	EvolutionChain    EvolutionChain      `json:"evolution_chain"`
+	Varieties         []Varieties         `json:"varieties"`
+}
+type Pokemon struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+type Varieties struct {
+	IsDefault bool    `json:"is_default"`
+	Pokemon   Pokemon `json:"pokemon"`
+}
+type EvolutionChain struct {
+	URL string `json:"url"`
+}
+type Names struct {
+	Name     string   `json:"name"`
+	Language Language `json:"language"`
 }
 type Language struct {
 	Name string `json:"name"`
@@ -51,3 +89,86 @@ type FlavorTextEntries struct {
 	Language   Language `json:"language"`
 	Version    Version  `json:"version"`
 }
+
+type RespEvolutionChain struct {
+	ID    int       `json:"id"`
+	Chain ChainLink `json:"chain"`
+}
+type Species struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+type ChainLink struct {
+	Species          Species            `json:"species"`
+	EvolutionDetails []EvolutionDetails `json:"evolution_details"`
+	EvolvesTo        []ChainLink        `json:"evolves_to"`
+}
+type EvolutionTrigger struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+type Item struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+type Location struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+type EvolutionDetails struct {
+	Trigger       EvolutionTrigger `json:"trigger"`
+	MinLevel      int              `json:"min_level"`
+	MinHappiness  int              `json:"min_happiness"`
+	MinAffection  int              `json:"min_affection"`
+	TimeOfDay     string           `json:"time_of_day"`
+	Item          *Item            `json:"item"`
+	HeldItem      *Item            `json:"held_item"`
+	KnownMoveType *Type            `json:"known_move_type"`
+	Location      *Location        `json:"location"`
+	TradeSpecies  *Species         `json:"trade_species"`
+}
+
+type RespAbility struct {
+	Name          string          `json:"name"`
+	EffectEntries []EffectEntries `json:"effect_entries"`
+}
+type EffectEntries struct {
+	Effect      string   `json:"effect"`
+	ShortEffect string   `json:"short_effect"`
+	Language    Language `json:"language"`
+}
+
+type RespPokemonForm struct {
+	Name      string  `json:"name"`
+	FormName  string  `json:"form_name"`
+	IsDefault bool    `json:"is_default"`
+	Names     []Names `json:"names"`
+	FormNames []Names `json:"form_names"`
+}
+
+type RespType struct {
+	Name            string          `json:"name"`
+	DamageRelations DamageRelations `json:"damage_relations"`
+	Pokemon         []TypePokemon   `json:"pokemon"`
+}
+type TypePokemon struct {
+	Slot    int     `json:"slot"`
+	Pokemon Pokemon `json:"pokemon"`
+}
+type DamageRelations struct {
+	DoubleDamageFrom []Type `json:"double_damage_from"`
+	HalfDamageFrom   []Type `json:"half_damage_from"`
+	NoDamageFrom     []Type `json:"no_damage_from"`
+	DoubleDamageTo   []Type `json:"double_damage_to"`
+	HalfDamageTo     []Type `json:"half_damage_to"`
+	NoDamageTo       []Type `json:"no_damage_to"`
+}
+
+type RespSpeciesList struct {
+	Count int `json:"count"`
+}
+type RespGeneration struct {
+	ID             int       `json:"id"`
+	Name           string    `json:"name"`
+	PokemonSpecies []Species `json:"pokemon_species"`
+}